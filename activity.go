@@ -0,0 +1,120 @@
+package parser
+
+import "time"
+
+// Heatmap counts messages by day of the week, Sunday first, and by hour of
+// the day
+type Heatmap [7][24]int
+
+// Period is the length of the buckets of a Series
+type Period string
+
+const (
+	PeriodDay   Period = "day"
+	PeriodWeek  Period = "week" // starting on Monday
+	PeriodMonth Period = "month"
+)
+
+// Series counts messages over consecutive periods, from the period of the
+// first message to that of the last, with the periods without messages
+// included. ByAuthor holds a row of counts for each of Authors.
+type Series struct {
+	Period   Period      `json:"period"`
+	Starts   []time.Time `json:"starts"`
+	Total    []int       `json:"total"`
+	Authors  []string    `json:"authors"`
+	ByAuthor [][]int     `json:"byAuthor"`
+}
+
+// GetHeatmap counts the messages of participants by day of the week and hour,
+// in the time zone of their dates
+func GetHeatmap(messages *[]Message) Heatmap {
+	var heatmap Heatmap
+	for _, message := range *messages {
+		if message.IsSystem {
+			continue
+		}
+		heatmap[message.Date.Weekday()][message.Date.Hour()]++
+	}
+	return heatmap
+}
+
+// periodStart returns the start of the period holding t, in the time zone of t
+func periodStart(t time.Time, period Period) time.Time {
+	year, month, day := t.Date()
+	switch period {
+	case PeriodWeek:
+		return time.Date(year, month, day-(int(t.Weekday())+6)%7, 0, 0, 0, 0, t.Location())
+	case PeriodMonth:
+		return time.Date(year, month, 1, 0, 0, 0, 0, t.Location())
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// nextPeriod returns the start of the period after the one starting at t
+func nextPeriod(t time.Time, period Period) time.Time {
+	switch period {
+	case PeriodWeek:
+		return t.AddDate(0, 0, 7)
+	case PeriodMonth:
+		return t.AddDate(0, 1, 0)
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+// dateRange returns the earliest and latest dates of the messages. Unlike
+// GetFirstAndLastMessageDates it does not rely on the order of the messages,
+// which Android exports can break with a later dated encryption notice on
+// the first line.
+func dateRange(messages []Message) (*time.Time, *time.Time) {
+	first, last := GetFirstAndLastMessageDates(&messages)
+	if first == nil {
+		return nil, nil
+	}
+	for _, message := range messages {
+		if message.Date.Before(*first) {
+			first = &message.Date
+		}
+		if message.Date.After(*last) {
+			last = &message.Date
+		}
+	}
+	return first, last
+}
+
+// GetSeries counts the messages of participants by day, week or month, in the
+// time zone of their dates, over the whole chat
+func GetSeries(messages *[]Message, period Period) *Series {
+	series := &Series{Period: period, Authors: GetAuthorsFromMessages(messages)}
+
+	first, last := dateRange(*messages)
+	if first == nil {
+		return series
+	}
+
+	index := make(map[int64]int)
+	for start := periodStart(*first, period); !start.After(*last); start = nextPeriod(start, period) {
+		index[start.Unix()] = len(series.Starts)
+		series.Starts = append(series.Starts, start)
+	}
+
+	authorIndex := make(map[string]int)
+	series.Total = make([]int, len(series.Starts))
+	series.ByAuthor = make([][]int, len(series.Authors))
+	for i, author := range series.Authors {
+		authorIndex[author] = i
+		series.ByAuthor[i] = make([]int, len(series.Starts))
+	}
+
+	for _, message := range *messages {
+		if message.IsSystem || message.Author == nil {
+			continue
+		}
+		// Dates in another time zone than the first one are moved to it
+		i := index[periodStart(message.Date.In(first.Location()), period).Unix()]
+		series.Total[i]++
+		series.ByAuthor[authorIndex[*message.Author]][i]++
+	}
+
+	return series
+}
@@ -0,0 +1,83 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+// TestActivity tests the heatmap and the time series of messages
+func TestActivity(t *testing.T) {
+	content := "28/06/2018, 23:30 - Anna created group \"Trip\"\n" +
+		"28/06/2018, 23:35 - Anna: Where do we go?\n" +
+		"29/06/2018, 09:06 - Bob: The beach\n" +
+		"09/07/2018, 09:30 - Anna: Ready?\n" +
+		"10/07/2018, 09:45 - Bob: Yes"
+
+	messages, err := ParseString(content, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("Heatmap", func(t *testing.T) {
+		heatmap := GetHeatmap(&messages)
+		if heatmap[time.Thursday][23] != 1 || heatmap[time.Friday][9] != 1 || heatmap[time.Monday][9] != 1 || heatmap[time.Tuesday][9] != 1 {
+			t.Errorf("Unexpected heatmap %v", heatmap)
+		}
+	})
+
+	t.Run("Time zone", func(t *testing.T) {
+		location, err := time.LoadLocation("Asia/Tokyo")
+		if err != nil {
+			t.Skipf("Time zone data unavailable: %v", err)
+		}
+		messages, err := ParseString(content, &ParseStringOptions{Location: location})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		heatmap := GetHeatmap(&messages)
+		if heatmap[time.Thursday][23] != 1 {
+			t.Errorf("Expected hours in the time zone of the chat, got %v", heatmap)
+		}
+		series := GetSeries(&messages, PeriodDay)
+		if series.Starts[0].Location() != location || len(series.Starts) != 13 {
+			t.Errorf("Expected 13 days in Tokyo time, got %v", series.Starts)
+		}
+	})
+
+	tests := []struct {
+		period Period
+		starts int
+		first  time.Time
+		total  []int
+		bob    []int
+	}{
+		{PeriodDay, 13, time.Date(2018, 6, 28, 0, 0, 0, 0, time.UTC), []int{1, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 1}, []int{0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}},
+		{PeriodWeek, 3, time.Date(2018, 6, 25, 0, 0, 0, 0, time.UTC), []int{2, 0, 2}, []int{1, 0, 1}},
+		{PeriodMonth, 2, time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC), []int{2, 2}, []int{1, 1}},
+	}
+
+	for _, test := range tests {
+		t.Run("Series by "+string(test.period), func(t *testing.T) {
+			series := GetSeries(&messages, test.period)
+			if len(series.Starts) != test.starts || !series.Starts[0].Equal(test.first) {
+				t.Fatalf("Expected %d periods from %v, got %v", test.starts, test.first, series.Starts)
+			}
+			if len(series.Authors) != 2 || series.Authors[1] != "Bob" {
+				t.Fatalf("Expected authors Anna and Bob, got %v", series.Authors)
+			}
+			for i := range test.total {
+				if series.Total[i] != test.total[i] || series.ByAuthor[1][i] != test.bob[i] {
+					t.Fatalf("Expected totals %v and %v for Bob, got %v and %v", test.total, test.bob, series.Total, series.ByAuthor[1])
+				}
+			}
+		})
+	}
+
+	t.Run("No messages", func(t *testing.T) {
+		series := GetSeries(&[]Message{}, PeriodDay)
+		if len(series.Starts) != 0 || len(series.Total) != 0 {
+			t.Errorf("Expected an empty series, got %+v", series)
+		}
+	})
+}
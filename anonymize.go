@@ -0,0 +1,420 @@
+package parser
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PIIKind names a kind of personal data replaced by Anonymize
+type PIIKind string
+
+const (
+	PIIPerson PIIKind = "person" // authors, names in system messages and mentions
+	PIIPhone  PIIKind = "phone"
+	PIIEmail  PIIKind = "email"
+	PIIURL    PIIKind = "url"
+	PIIIBAN   PIIKind = "iban"
+	PIIFile   PIIKind = "file" // attachment file names
+)
+
+// regexPII matches, in this order of groups, the URLs, emails, IBANs, phone
+// numbers and mentions masked by Anonymize. Numbers are checked by isPhone
+// before being masked.
+var regexPII = regexp.MustCompile(`((?i:\b(?:https?://|www\.))\S+)` +
+	`|([\p{L}\p{N}._%+-]+@[\p{L}\p{N}-]+(?:\.[\p{L}\p{N}-]+)*\.\p{L}{2,})` +
+	`|(\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,3})?\b)` +
+	`|((?:\+|\b)\d+(?:[ .\-]?\(?\d+\)?)*)` +
+	`|(@[\p{L}\p{N}_]+)`)
+
+// regexPhoneName matches names made of a phone number, which WhatsApp shows
+// for people missing from the contacts
+var regexPhoneName = regexp.MustCompile(`^\+?[\d\s().-]+$`)
+
+// AnonymizeOptions configures Anonymize
+type AnonymizeOptions struct {
+	// Key keys the pseudonyms. They are stable for a key, so that the same
+	// person gets the same pseudonym in several chats, but without a secret
+	// key anyone can check whether a guessed name hides behind a pseudonym.
+	Key []byte `json:"-"`
+}
+
+// Pseudonym is an entry of the mapping table returned by Anonymize
+type Pseudonym struct {
+	Kind      PIIKind `json:"kind"`
+	Original  string  `json:"original"`
+	Pseudonym string  `json:"pseudonym"`
+}
+
+// anonymizer holds the pseudonyms given so far
+type anonymizer struct {
+	key []byte
+	// pseudonyms maps the kind and normalized value of each original to its
+	// pseudonym, and used holds the pseudonyms given
+	pseudonyms map[PIIKind]map[string]string
+	used       map[string]bool
+	mapping    []Pseudonym
+	// names are the known names of people, longest first, to find mentions
+	// of names with spaces and the names in system messages
+	names   []string
+	owner   map[string]bool
+	locales []*Locale
+}
+
+// Anonymize returns a copy of the messages with the authors replaced by
+// pseudonyms like "Person-3fa29c", and with the phone numbers, emails, URLs,
+// IBANs and @mentions of the text masked by tokens like "[phone-0b51e2]".
+// The names of system messages, the file names of attachments and the
+// payloads are replaced to match, so that the copy written with Write parses
+// to the same messages. Names written out in the text without an "@" are
+// left as they are. The mapping table from the originals to the pseudonyms
+// is returned sorted by kind and original.
+func Anonymize(messages []Message, opts *AnonymizeOptions) ([]Message, []Pseudonym) {
+	if opts == nil {
+		opts = &AnonymizeOptions{}
+	}
+	a := &anonymizer{
+		key:        opts.Key,
+		pseudonyms: make(map[PIIKind]map[string]string),
+		used:       make(map[string]bool),
+		owner:      make(map[string]bool),
+		locales:    Locales(),
+	}
+	for _, locale := range a.locales {
+		for _, word := range locale.Owner {
+			a.owner[word] = true
+		}
+	}
+	a.collectNames(messages)
+
+	result := make([]Message, len(messages))
+	for i, message := range messages {
+		result[i] = a.message(message)
+	}
+
+	sort.Slice(a.mapping, func(i, j int) bool {
+		if a.mapping[i].Kind != a.mapping[j].Kind {
+			return a.mapping[i].Kind < a.mapping[j].Kind
+		}
+		return a.mapping[i].Original < a.mapping[j].Original
+	})
+	return result, a.mapping
+}
+
+// collectNames gathers the authors and the people named by system events
+func (a *anonymizer) collectNames(messages []Message) {
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if name != "" && !a.owner[name] && !seen[name] {
+			seen[name] = true
+			a.names = append(a.names, name)
+		}
+	}
+
+	for _, message := range messages {
+		if message.Author != nil {
+			add(*message.Author)
+		}
+		for _, name := range eventNames(message.Event) {
+			add(name)
+		}
+	}
+
+	sort.Slice(a.names, func(i, j int) bool {
+		if len(a.names[i]) != len(a.names[j]) {
+			return len(a.names[i]) > len(a.names[j])
+		}
+		return a.names[i] < a.names[j]
+	})
+}
+
+// eventNames returns the actor and targets of an event
+func eventNames(event *SystemEvent) []string {
+	if event == nil {
+		return nil
+	}
+	names := event.Targets
+	if event.Actor != nil {
+		names = append([]string{*event.Actor}, names...)
+	}
+	return names
+}
+
+// message anonymizes a message
+func (a *anonymizer) message(message Message) Message {
+	if message.Author != nil {
+		author := a.person(*message.Author)
+		message.Author = &author
+	}
+
+	text := message.Message
+	if message.IsSystem {
+		// Names are replaced first, as they may be phone numbers
+		text = a.systemNames(text, message.Event != nil)
+	}
+
+	if message.Attachment != nil {
+		attachment := *message.Attachment
+		if attachment.FileName != "" {
+			fileName := a.fileName(&attachment)
+			// The file name is kept out of the masking, which could take
+			// the digits of media names for a phone number
+			if i := strings.Index(text, attachment.FileName); i >= 0 {
+				text = a.mask(text[:i]) + fileName + a.mask(text[i+len(attachment.FileName):])
+			} else {
+				text = a.mask(text)
+			}
+			if attachment.Path != "" {
+				attachment.Path = path.Join(path.Dir(attachment.Path), fileName)
+			}
+			attachment.FileName = fileName
+		} else {
+			text = a.mask(text)
+		}
+		attachment.Caption = a.mask(attachment.Caption)
+		message.Attachment = &attachment
+	} else {
+		text = a.mask(text)
+	}
+	message.Message = text
+
+	if message.Event != nil {
+		message.Event = parseSystemEvent(text, a.locales)
+	}
+
+	if message.Payload != nil {
+		payload := parsePayload(text, message.Attachment)
+		if payload != nil && payload.Kind == PayloadContactCard && message.Payload.ContactCard != nil {
+			for _, phone := range message.Payload.ContactCard.Phones {
+				payload.ContactCard.Phones = append(payload.ContactCard.Phones, a.phone(phone))
+			}
+		}
+		message.Payload = payload
+	}
+
+	return message
+}
+
+// systemNames replaces the names of a system message: the chat name iOS puts
+// before it, and the actor and targets of its event. Without an event, the
+// known names written out as whole words are replaced. Only these spans are
+// replaced, so that names found in other words or in the pseudonyms are left
+// alone.
+func (a *anonymizer) systemNames(text string, hasEvent bool) string {
+	var spans [][2]int
+	start := 0
+	if loc := regexSystemPrefix.FindStringIndex(text); loc != nil {
+		name := strings.TrimSuffix(strings.TrimRightFunc(text[:loc[1]], isSpaceOrMark), ":")
+		spans = append(spans, [2]int{0, len(name)})
+		start = loc[1]
+	}
+	if hasEvent {
+		_, names := findSystemEvent(text, a.locales)
+		spans = append(spans, names...)
+	} else {
+		spans = append(spans, a.nameSpans(text, start)...)
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, span := range spans {
+		name := text[span[0]:span[1]]
+		if name == "" || a.owner[name] {
+			continue
+		}
+		b.WriteString(text[last:span[0]])
+		b.WriteString(a.person(name))
+		last = span[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// nameSpans returns the spans of the known names written out as whole words
+// in a text from the given offset, preferring the longest names
+func (a *anonymizer) nameSpans(text string, offset int) [][2]int {
+	var spans [][2]int
+	for i := offset; i < len(text); {
+		previous, _ := utf8.DecodeLastRuneInString(text[:i])
+		if i == 0 || !unicode.IsLetter(previous) && !unicode.IsDigit(previous) {
+			if name := a.mentionedName(text[i:]); name != "" {
+				spans = append(spans, [2]int{i, i + len(name)})
+				i += len(name)
+				continue
+			}
+		}
+		_, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+	}
+	return spans
+}
+
+// mask replaces the URLs, emails, IBANs, phone numbers and mentions of a text
+func (a *anonymizer) mask(text string) string {
+	matches := regexPII.FindAllStringSubmatchIndex(text, -1)
+	if matches == nil {
+		return text
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		// The match is part of a name with spaces already replaced
+		if start < last {
+			continue
+		}
+		b.WriteString(text[last:start])
+		value := text[start:end]
+
+		switch {
+		case m[2] >= 0:
+			value = strings.TrimRight(value, `.,;:!?)'"`)
+			end = start + len(value)
+			b.WriteString(a.pseudonym(PIIURL, value, value))
+		case m[4] >= 0:
+			b.WriteString(a.pseudonym(PIIEmail, value, strings.ToLower(value)))
+		case m[6] >= 0:
+			b.WriteString(a.pseudonym(PIIIBAN, value, strings.ReplaceAll(value, " ", "")))
+		case m[8] >= 0:
+			if isPhone(value) {
+				b.WriteString(a.phone(value))
+			} else {
+				b.WriteString(value)
+			}
+		case m[10] >= 0:
+			name := a.mentionedName(text[start+1:])
+			if name == "" {
+				name = value[1:]
+			}
+			end = start + 1 + len(name)
+			b.WriteString("@" + a.person(name))
+		}
+		last = end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// mentionedName returns the longest known name starting the text, if it is
+// followed by neither a letter nor a digit
+func (a *anonymizer) mentionedName(text string) string {
+	for _, name := range a.names {
+		if !strings.HasPrefix(text, name) {
+			continue
+		}
+		next, _ := utf8.DecodeRuneInString(text[len(name):])
+		if next == utf8.RuneError || !unicode.IsLetter(next) && !unicode.IsDigit(next) {
+			return name
+		}
+	}
+	return ""
+}
+
+// fileName returns the pseudonym of the file name of an attachment. The
+// prefix of exported media names is kept, as it tells the kind of the media,
+// and contact cards are named after the pseudonym of the contact.
+func (a *anonymizer) fileName(attachment *Attachment) string {
+	extension := path.Ext(attachment.FileName)
+
+	if attachment.Kind == AttachmentContactCard {
+		prefix := regexContactFileName.FindString(attachment.FileName)
+		name := strings.TrimSuffix(attachment.FileName[len(prefix):], extension)
+		return a.pseudonym(PIIFile, attachment.FileName, attachment.FileName, func(string) string {
+			return prefix + a.person(name) + extension
+		})
+	}
+
+	prefix := regexMediaName.FindString(attachment.FileName)
+	if prefix == "" {
+		prefix = "file-"
+	}
+	return a.pseudonym(PIIFile, attachment.FileName, attachment.FileName, func(hash string) string {
+		return prefix + hash + extension
+	})
+}
+
+// person returns the pseudonym of a person. Names made of a phone number are
+// compared by their digits, so that they match mentions by number.
+func (a *anonymizer) person(name string) string {
+	key := name
+	if regexPhoneName.MatchString(name) {
+		key = digits(name)
+	}
+	return a.pseudonym(PIIPerson, name, key)
+}
+
+// phone returns the masking token of a phone number
+func (a *anonymizer) phone(number string) string {
+	return a.pseudonym(PIIPhone, number, digits(number))
+}
+
+// pseudonym returns the pseudonym of the value of a kind with the given
+// normalized key, made by default of the kind and the start of the HMAC of
+// the key, lengthened on collisions
+func (a *anonymizer) pseudonym(kind PIIKind, original string, key string, format ...func(hash string) string) string {
+	if a.pseudonyms[kind] == nil {
+		a.pseudonyms[kind] = make(map[string]string)
+	}
+	if pseudonym, ok := a.pseudonyms[kind][key]; ok {
+		return pseudonym
+	}
+
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(kind))
+	mac.Write([]byte{0})
+	mac.Write([]byte(key))
+	hash := hex.EncodeToString(mac.Sum(nil))
+
+	for n := 6; n <= len(hash); n += 2 {
+		var pseudonym string
+		switch {
+		case len(format) > 0:
+			pseudonym = format[0](hash[:n])
+		case kind == PIIPerson:
+			pseudonym = "Person-" + hash[:n]
+		default:
+			pseudonym = "[" + string(kind) + "-" + hash[:n] + "]"
+		}
+		if a.used[pseudonym] {
+			continue
+		}
+
+		a.used[pseudonym] = true
+		a.pseudonyms[kind][key] = pseudonym
+		a.mapping = append(a.mapping, Pseudonym{Kind: kind, Original: original, Pseudonym: pseudonym})
+		return pseudonym
+	}
+	panic("parser: no pseudonym left for " + original)
+}
+
+// isPhone tells whether a number is a phone number: international numbers
+// start with "+" or "00" and have 8 to 15 digits, national ones start with a
+// single 0 and have 9 to 15 digits
+func isPhone(number string) bool {
+	n := len(digits(number))
+	switch {
+	case strings.HasPrefix(number, "+"), strings.HasPrefix(number, "00"):
+		return n >= 8 && n <= 15
+	case strings.HasPrefix(number, "0"):
+		return n >= 9 && n <= 15
+	}
+	return false
+}
+
+// digits returns the digits of a text
+func digits(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
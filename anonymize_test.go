@@ -0,0 +1,251 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestAnonymize tests the pseudonyms and masking of Anonymize
+func TestAnonymize(t *testing.T) {
+	content := "11/06/2018, 09:00 - Anna Smith created group \"Trip\"\n" +
+		"11/06/2018, 09:01 - Anna Smith added Bob and +33 6 99 88 77 66\n" +
+		"11/06/2018, 09:02 - Anna Smith: Call me at +33 6 99 88 77 66 or Anna.Smith@example.com\n" +
+		"11/06/2018, 09:03 - Bob: @Anna Smith see https://example.com/trip, and pay to FR76 3000 6000 0112 3456 7890 189.\n" +
+		"11/06/2018, 09:04 - +33 6 99 88 77 66: @33699887766 meet at 10:30 on 11.06.2018 with 1 000 000 people\n" +
+		"11/06/2018, 09:05 - Bob: IMG-20180611-WA0001.jpg (file attached)\n" +
+		"Call 0612345678\n" +
+		"11/06/2018, 09:06 - Bob: anna.smith@EXAMPLE.com\n" +
+		"11/06/2018, 09:07 - You removed Bob"
+
+	options := &ParseStringOptions{ParseAttachments: true}
+	messages, err := ParseString(content, options)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	anonymized, mapping := Anonymize(messages, &AnonymizeOptions{Key: []byte("secret")})
+	pseudonyms := make(map[PIIKind]map[string]string)
+	for _, p := range mapping {
+		if pseudonyms[p.Kind] == nil {
+			pseudonyms[p.Kind] = make(map[string]string)
+		}
+		pseudonyms[p.Kind][p.Original] = p.Pseudonym
+	}
+	anna := pseudonyms[PIIPerson]["Anna Smith"]
+	bob := pseudonyms[PIIPerson]["Bob"]
+	number := pseudonyms[PIIPerson]["+33 6 99 88 77 66"]
+	phone := pseudonyms[PIIPhone]["+33 6 99 88 77 66"]
+
+	t.Run("pseudonyms", func(t *testing.T) {
+		if !strings.HasPrefix(anna, "Person-") || bob == "" || number == "" || anna == bob {
+			t.Fatalf("Expected distinct pseudonyms, got %v", pseudonyms[PIIPerson])
+		}
+		if *anonymized[2].Author != anna || *anonymized[4].Author != number {
+			t.Errorf("Expected the authors replaced, got %q and %q", *anonymized[2].Author, *anonymized[4].Author)
+		}
+		if *messages[2].Author != "Anna Smith" {
+			t.Errorf("Expected the messages left unchanged, got %q", *messages[2].Author)
+		}
+
+		again, _ := Anonymize(messages, &AnonymizeOptions{Key: []byte("secret")})
+		if !reflect.DeepEqual(again, anonymized) {
+			t.Errorf("Expected the same pseudonyms with the same key")
+		}
+		other, _ := Anonymize(messages, nil)
+		if *other[2].Author == anna {
+			t.Errorf("Expected other pseudonyms without the key")
+		}
+	})
+
+	t.Run("masking", func(t *testing.T) {
+		email := pseudonyms[PIIEmail]["Anna.Smith@example.com"]
+		tests := []string{
+			"Call me at " + phone + " or " + email,
+			"@" + anna + " see " + pseudonyms[PIIURL]["https://example.com/trip"] + ", and pay to " +
+				pseudonyms[PIIIBAN]["FR76 3000 6000 0112 3456 7890 189"] + ".",
+			"@" + number + " meet at 10:30 on 11.06.2018 with 1 000 000 people",
+		}
+		for i, expected := range tests {
+			if text := anonymized[i+2].Message; text != expected {
+				t.Errorf("Expected %q, got %q", expected, text)
+			}
+		}
+
+		if anonymized[6].Message != email {
+			t.Errorf("Expected emails compared without case, got %q", anonymized[6].Message)
+		}
+		if !strings.HasPrefix(phone, "[phone-") || !strings.HasPrefix(email, "[email-") {
+			t.Errorf("Unexpected tokens %q and %q", phone, email)
+		}
+	})
+
+	t.Run("system messages and attachments", func(t *testing.T) {
+		added := anonymized[1].Event
+		if added == nil || *added.Actor != anna || !reflect.DeepEqual(added.Targets, []string{bob, number}) {
+			t.Errorf("Expected the names of the event replaced, got %+v", added)
+		}
+		removed := anonymized[7]
+		if removed.Message != "You removed "+bob || *removed.Event.Actor != "You" {
+			t.Errorf("Expected the owner kept, got %q", removed.Message)
+		}
+
+		attachment := anonymized[5].Attachment
+		if attachment == nil || !strings.HasPrefix(attachment.FileName, "IMG-20180611-WA") || attachment.FileName == "IMG-20180611-WA0001.jpg" {
+			t.Fatalf("Expected the file renamed, got %+v", attachment)
+		}
+		if attachment.Caption != "Call "+pseudonyms[PIIPhone]["0612345678"] || attachment.Kind != AttachmentImage {
+			t.Errorf("Unexpected attachment %+v", attachment)
+		}
+	})
+
+	t.Run("names inside other names", func(t *testing.T) {
+		// "Per" is part of "Peralta" and of every person pseudonym
+		messages, err := ParseString("11/06/2018, 09:00 - Per added Anna\n"+
+			"11/06/2018, 09:01 - Peralta added Per and Anna", nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		anonymized, mapping := Anonymize(messages, &AnonymizeOptions{Key: []byte("secret")})
+		names := make(map[string]string)
+		for _, p := range mapping {
+			names[p.Original] = p.Pseudonym
+		}
+		per, anna, peralta := names["Per"], names["Anna"], names["Peralta"]
+
+		expected := []string{per + " added " + anna, peralta + " added " + per + " and " + anna}
+		for i, text := range expected {
+			if anonymized[i].Message != text {
+				t.Errorf("Expected %q, got %q", text, anonymized[i].Message)
+			}
+		}
+
+		text, err := Format(anonymized, DialectAndroid)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		reparsed, err := ParseString(text, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if targets := reparsed[1].Event.Targets; !reflect.DeepEqual(targets, []string{per, anna}) {
+			t.Errorf("Expected the targets %v, got %v", []string{per, anna}, targets)
+		}
+	})
+
+	t.Run("system messages without an event", func(t *testing.T) {
+		messages, err := ParseString("13/06/2018, 21:25 - Anna Smith: Hi\n"+
+			"13/06/2018, 21:26 - Anna Smith pinned a message", nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !messages[1].IsSystem || messages[1].Event != nil {
+			t.Fatalf("Expected a system message without an event, got %+v", messages[1])
+		}
+
+		anonymized, _ := Anonymize(messages, nil)
+		if expected := *anonymized[0].Author + " pinned a message"; anonymized[1].Message != expected {
+			t.Errorf("Expected %q, got %q", expected, anonymized[1].Message)
+		}
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		text, err := Format(anonymized, DialectAndroid)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for _, original := range []string{"Anna", "Bob", "99 88", "example.com", "FR76", "WA0001"} {
+			if strings.Contains(text, original) {
+				t.Errorf("Expected %q redacted from %q", original, text)
+			}
+		}
+
+		reparsed, err := ParseString(text, options)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(reparsed, anonymized) {
+			t.Errorf("Expected the redacted chat to parse to the same messages, got %+v", reparsed)
+		}
+	})
+
+	t.Run("test data", func(t *testing.T) {
+		paths, err := filepath.Glob("test_data/*.txt")
+		if err != nil || len(paths) == 0 {
+			t.Fatalf("No test data: %v", err)
+		}
+
+		for _, path := range paths {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			messages, err := ParseString(string(content), nil)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			// The authors, the names of events and the chat names iOS puts
+			// before system messages
+			names := make(map[string]bool)
+			for _, message := range messages {
+				if message.Author != nil {
+					names[*message.Author] = true
+				}
+				for _, name := range eventNames(message.Event) {
+					names[name] = true
+				}
+				if prefix := regexSystemPrefix.FindString(message.Message); prefix != "" {
+					names[strings.TrimSuffix(strings.TrimRightFunc(prefix, isSpaceOrMark), ":")] = true
+				}
+			}
+
+			anonymized, _ := Anonymize(messages, nil)
+			text, err := Format(anonymized, DialectAndroid)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, locale := range Locales() {
+				for _, word := range locale.Owner {
+					delete(names, word)
+				}
+			}
+			for name := range names {
+				if strings.Contains(text, name) {
+					t.Errorf("Expected %q redacted from %s", name, path)
+				}
+			}
+		}
+	})
+}
+
+// TestAnonymizePayloads tests the anonymization of contact cards and locations
+func TestAnonymizePayloads(t *testing.T) {
+	content := "\u200E[13/06/2018, 21:26:00] Bob: location: https://maps.google.com/?q=52.2297,21.0122\n" +
+		"\u200E[13/06/2018, 21:28:00] Bob: \u200E<attached: 00000016-John Doe.vcf>"
+
+	messages, err := ParseString(content, &ParseStringOptions{ParseAttachments: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	messages[1].Payload.ContactCard.Phones = []string{"+48 601 234 567"}
+
+	anonymized, mapping := Anonymize(messages, nil)
+	if anonymized[0].Payload != nil {
+		t.Errorf("Expected the location dropped with its URL, got %+v", anonymized[0].Payload)
+	}
+
+	card := anonymized[1].Payload.ContactCard
+	if !strings.HasPrefix(card.Name, "Person-") || len(card.Phones) != 1 || !strings.HasPrefix(card.Phones[0], "[phone-") {
+		t.Errorf("Expected the contact card anonymized, got %+v", card)
+	}
+	if fileName := anonymized[1].Attachment.FileName; fileName != "00000016-"+card.Name+".vcf" {
+		t.Errorf("Expected the file named after the contact, got %q", fileName)
+	}
+	if len(mapping) != 5 {
+		t.Errorf("Expected 5 pseudonyms, got %+v", mapping)
+	}
+}
@@ -0,0 +1,149 @@
+package parser
+
+import (
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// regexMediaName matches the names WhatsApp gives to exported media, like
+// "00000012-PHOTO-2018-11-29-10-51-11.jpg" on iOS and
+// "IMG-20180611-WA0002.jpg" on Android
+var regexMediaName = regexp.MustCompile(`^(?:\d+-(PHOTO|VIDEO|AUDIO|STICKER|GIF)-|(IMG|VID|AUD|PTT|STK|DOC)-\d{8}-WA)`)
+
+// regexDocumentInfo matches the details iOS writes before a document, like
+// "report.pdf • 3 pages"
+var regexDocumentInfo = regexp.MustCompile(`•\s*(\d+)\s+\p{L}+$`)
+
+// mediaNameKinds maps the media type in exported file names to a kind
+var mediaNameKinds = map[string]AttachmentKind{
+	"PHOTO":   AttachmentImage,
+	"IMG":     AttachmentImage,
+	"VIDEO":   AttachmentVideo,
+	"VID":     AttachmentVideo,
+	"AUDIO":   AttachmentAudio,
+	"AUD":     AttachmentAudio,
+	"PTT":     AttachmentVoiceNote,
+	"STICKER": AttachmentSticker,
+	"STK":     AttachmentSticker,
+	"GIF":     AttachmentGIF,
+	"DOC":     AttachmentDocument,
+}
+
+// attachmentType is what a file extension tells about an attachment
+type attachmentType struct {
+	kind     AttachmentKind
+	mimeType string
+}
+
+// attachmentExtensions maps lower case file extensions to their kind and
+// MIME type. The table is fixed rather than taken from the system so that
+// parsing gives the same result everywhere.
+var attachmentExtensions = map[string]attachmentType{
+	"jpg":  {AttachmentImage, "image/jpeg"},
+	"jpeg": {AttachmentImage, "image/jpeg"},
+	"png":  {AttachmentImage, "image/png"},
+	"heic": {AttachmentImage, "image/heic"},
+	"webp": {AttachmentImage, "image/webp"},
+	"gif":  {AttachmentGIF, "image/gif"},
+	"mp4":  {AttachmentVideo, "video/mp4"},
+	"mov":  {AttachmentVideo, "video/quicktime"},
+	"3gp":  {AttachmentVideo, "video/3gpp"},
+	"opus": {AttachmentVoiceNote, "audio/ogg"},
+	"ogg":  {AttachmentAudio, "audio/ogg"},
+	"m4a":  {AttachmentAudio, "audio/mp4"},
+	"mp3":  {AttachmentAudio, "audio/mpeg"},
+	"aac":  {AttachmentAudio, "audio/aac"},
+	"amr":  {AttachmentAudio, "audio/amr"},
+	"wav":  {AttachmentAudio, "audio/wav"},
+	"vcf":  {AttachmentContactCard, "text/vcard"},
+	"pdf":  {AttachmentDocument, "application/pdf"},
+	"txt":  {AttachmentDocument, "text/plain"},
+	"csv":  {AttachmentDocument, "text/csv"},
+	"rtf":  {AttachmentDocument, "application/rtf"},
+	"zip":  {AttachmentDocument, "application/zip"},
+	"doc":  {AttachmentDocument, "application/msword"},
+	"docx": {AttachmentDocument, "application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+	"xls":  {AttachmentDocument, "application/vnd.ms-excel"},
+	"xlsx": {AttachmentDocument, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+	"ppt":  {AttachmentDocument, "application/vnd.ms-powerpoint"},
+	"pptx": {AttachmentDocument, "application/vnd.openxmlformats-officedocument.presentationml.presentation"},
+}
+
+// attachmentKind returns the kind of an attachment from its file name,
+// falling back to its extension
+func attachmentKind(fileName string, extension string) AttachmentKind {
+	byExtension, known := attachmentExtensions[extension]
+
+	if matches := regexMediaName.FindStringSubmatch(fileName); matches != nil {
+		kind := mediaNameKinds[matches[1]+matches[2]]
+		// iOS saves voice notes as audio files in the Opus format
+		if kind == AttachmentAudio && byExtension.kind == AttachmentVoiceNote {
+			return AttachmentVoiceNote
+		}
+		return kind
+	}
+
+	if !known {
+		return AttachmentUnknown
+	}
+	return byExtension.kind
+}
+
+// trimMarks trims spaces and directional marks
+func trimMarks(s string) string {
+	return strings.TrimFunc(s, isSpaceOrMark)
+}
+
+// describe fills in what the file name and the text around the attachment
+// marker tell about the attachment
+func (a *Attachment) describe(before string, after string) {
+	a.Extension = strings.ToLower(strings.TrimPrefix(path.Ext(a.FileName), "."))
+	a.Kind = attachmentKind(a.FileName, a.Extension)
+	a.MIMEType = attachmentExtensions[a.Extension].mimeType
+
+	before = trimMarks(before)
+	if matches := regexDocumentInfo.FindStringSubmatch(before); matches != nil {
+		a.Pages, _ = strconv.Atoi(matches[1])
+		// The rest is the document title, already in the file name
+		before = ""
+	}
+
+	var caption []string
+	for _, text := range []string{before, trimMarks(after)} {
+		if text != "" {
+			caption = append(caption, text)
+		}
+	}
+	a.Caption = strings.Join(caption, "\n")
+}
+
+// parseOmittedMedia parses the placeholder written in place of a file in
+// exports made without media. iOS may write document details before the
+// placeholder, and a caption may follow on the next lines.
+func parseOmittedMedia(message string, locales []*Locale) *Attachment {
+	placeholder, caption, _ := strings.Cut(message, "\n")
+	placeholder = trimMarks(placeholder)
+	lower := strings.ToLower(placeholder)
+
+	for _, locale := range locales {
+		for text, kind := range locale.OmittedMedia {
+			if !strings.HasSuffix(lower, text) {
+				continue
+			}
+
+			attachment := &Attachment{Kind: kind, Omitted: true, Caption: trimMarks(caption)}
+			details := trimMarks(lower[:len(lower)-len(text)])
+			if details == "" {
+				return attachment
+			}
+			if matches := regexDocumentInfo.FindStringSubmatch(details); matches != nil {
+				attachment.Pages, _ = strconv.Atoi(matches[1])
+				return attachment
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package parser
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+// TestAttachmentKinds tests what is parsed from attachments
+func TestAttachmentKinds(t *testing.T) {
+	tests := []struct {
+		message string
+		expect  Attachment
+	}{
+		{
+			message: "\u200E<attached: 00000012-PHOTO-2018-11-29-10-51-11.jpg>",
+			expect:  Attachment{FileName: "00000012-PHOTO-2018-11-29-10-51-11.jpg", Kind: AttachmentImage, Extension: "jpg", MIMEType: "image/jpeg"},
+		},
+		{
+			message: "\u200E<attached: 00000020-AUDIO-2018-11-29-10-52-01.opus>",
+			expect:  Attachment{FileName: "00000020-AUDIO-2018-11-29-10-52-01.opus", Kind: AttachmentVoiceNote, Extension: "opus", MIMEType: "audio/ogg"},
+		},
+		{
+			message: "\u200E<attached: 00000021-STICKER-2018-11-29-10-52-01.webp>",
+			expect:  Attachment{FileName: "00000021-STICKER-2018-11-29-10-52-01.webp", Kind: AttachmentSticker, Extension: "webp", MIMEType: "image/webp"},
+		},
+		{
+			message: "\u200E<attached: 00000022-GIF-2018-11-29-10-52-01.mp4>",
+			expect:  Attachment{FileName: "00000022-GIF-2018-11-29-10-52-01.mp4", Kind: AttachmentGIF, Extension: "mp4", MIMEType: "video/mp4"},
+		},
+		{
+			message: "\u200EReport.pdf • 3 pages \u200E<attached: 00000015-Report.pdf>",
+			expect:  Attachment{FileName: "00000015-Report.pdf", Kind: AttachmentDocument, Extension: "pdf", MIMEType: "application/pdf", Pages: 3},
+		},
+		{
+			message: "\u200E<attached: 00000016-John Doe.vcf>",
+			expect:  Attachment{FileName: "00000016-John Doe.vcf", Kind: AttachmentContactCard, Extension: "vcf", MIMEType: "text/vcard"},
+		},
+		{
+			message: "IMG-20180611-WA0002.JPG (file attached)\nLook at this",
+			expect:  Attachment{FileName: "IMG-20180611-WA0002.JPG", Kind: AttachmentImage, Extension: "jpg", MIMEType: "image/jpeg", Caption: "Look at this"},
+		},
+		{
+			message: "PTT-20180611-WA0003.opus (file attached)",
+			expect:  Attachment{FileName: "PTT-20180611-WA0003.opus", Kind: AttachmentVoiceNote, Extension: "opus", MIMEType: "audio/ogg"},
+		},
+		{
+			message: "STK-20180611-WA0004.webp (file attached)",
+			expect:  Attachment{FileName: "STK-20180611-WA0004.webp", Kind: AttachmentSticker, Extension: "webp", MIMEType: "image/webp"},
+		},
+		{
+			message: "notes.xyz (file attached)",
+			expect:  Attachment{FileName: "notes.xyz", Kind: AttachmentUnknown, Extension: "xyz"},
+		},
+	}
+
+	for _, test := range tests {
+		attachment := parseMessageAttachment(test.message)
+		if attachment == nil {
+			t.Errorf("Expected an attachment in %q", test.message)
+			continue
+		}
+		if !reflect.DeepEqual(*attachment, test.expect) {
+			t.Errorf("parseMessageAttachment(%q) = %+v, want %+v", test.message, *attachment, test.expect)
+		}
+	}
+}
+
+// TestOmittedMedia tests the placeholders of exports made without media
+func TestOmittedMedia(t *testing.T) {
+	tests := []struct {
+		message string
+		expect  *Attachment
+	}{
+		{"<Media omitted>", &Attachment{Kind: AttachmentUnknown, Omitted: true}},
+		{"\u200Eimage omitted", &Attachment{Kind: AttachmentImage, Omitted: true}},
+		{"\u200EGIF omitted", &Attachment{Kind: AttachmentGIF, Omitted: true}},
+		{"\u200EContact card omitted", &Attachment{Kind: AttachmentContactCard, Omitted: true}},
+		{"\u200EReport.pdf • 3 pages \u200Edocument omitted", &Attachment{Kind: AttachmentDocument, Omitted: true, Pages: 3}},
+		{"<Media omitted>\nLook at this", &Attachment{Kind: AttachmentUnknown, Omitted: true, Caption: "Look at this"}},
+		{"Bild weggelassen", &Attachment{Kind: AttachmentImage, Omitted: true}},
+		{"<Multimedia omitido>", &Attachment{Kind: AttachmentUnknown, Omitted: true}},
+		{"my favourite video omitted", nil},
+		{"image omitted, sorry", nil},
+	}
+
+	for _, test := range tests {
+		attachment := parseOmittedMedia(test.message, Locales())
+		if !reflect.DeepEqual(attachment, test.expect) {
+			t.Errorf("parseOmittedMedia(%q) = %+v, want %+v", test.message, attachment, test.expect)
+		}
+	}
+
+	t.Run("Forced locale", func(t *testing.T) {
+		if attachment := parseOmittedMedia("Bild weggelassen", []*Locale{LookupLocale("en")}); attachment != nil {
+			t.Errorf("Expected German placeholders to be ignored for English, got %+v", attachment)
+		}
+	})
+
+	t.Run("Test data", func(t *testing.T) {
+		fileContents, err := os.ReadFile("test_data/english_android-unsaved_contacts.txt")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		messages, err := ParseString(string(fileContents), &ParseStringOptions{ParseAttachments: true})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if attachment := messages[1].Attachment; attachment == nil || !attachment.Omitted {
+			t.Errorf("Expected omitted media, got %+v", attachment)
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Results before and after the header lexer replaced the header regexes,
+// medians of go test -bench . -benchmem -count 3 on one machine:
+//
+//	                                      before                      after
+//	ParseString/default                   240 ms/op  28.0 MB  338k    83 ms/op  14.4 MB  180k
+//	ParseString/english_android-unsaved   760 ms/op   110 MB  1278k  290 ms/op  54.9 MB  638k
+//	ParseString/english_iphone-saved      530 ms/op  85.7 MB  987k   205 ms/op  43.0 MB  510k
+//	ParseReader/default                   155 ms/op  21.3 MB  327k    72 ms/op   7.2 MB  169k
+//	ParseReader/english_android-unsaved   540 ms/op  80.5 MB  1283k  258 ms/op  23.1 MB  643k
+//	ParseReader/english_iphone-saved      445 ms/op  62.3 MB  989k   121 ms/op  19.9 MB  512k
+
+// benchmarkRepeats is the number of copies of each test_data chat parsed by
+// the benchmarks
+const benchmarkRepeats = 1000
+
+// benchmarkChats returns the chats of test_data, each repeated to make a
+// long chat
+func benchmarkChats(b *testing.B) map[string]string {
+	b.Helper()
+
+	paths, err := filepath.Glob("test_data/*.txt")
+	if err != nil || len(paths) == 0 {
+		b.Fatalf("No test data: %v", err)
+	}
+
+	chats := make(map[string]string)
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			b.Fatalf("Unexpected error: %v", err)
+		}
+		chat := strings.TrimRight(string(content), "\n") + "\n"
+		chats[strings.TrimSuffix(filepath.Base(path), ".txt")] = strings.Repeat(chat, benchmarkRepeats)
+	}
+	return chats
+}
+
+// BenchmarkParseString measures parsing whole chats
+func BenchmarkParseString(b *testing.B) {
+	for name, chat := range benchmarkChats(b) {
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(chat)))
+			for i := 0; i < b.N; i++ {
+				if _, err := ParseString(chat, &ParseStringOptions{ParseAttachments: true}); err != nil {
+					b.Fatalf("Unexpected error: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkParseStringConcurrently measures parsing whole chats with a
+// goroutine per CPU building the messages
+func BenchmarkParseStringConcurrently(b *testing.B) {
+	for name, chat := range benchmarkChats(b) {
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(chat)))
+			for i := 0; i < b.N; i++ {
+				if _, err := ParseString(chat, &ParseStringOptions{ParseAttachments: true, Concurrency: -1}); err != nil {
+					b.Fatalf("Unexpected error: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkParseReader measures streaming chats message by message
+func BenchmarkParseReader(b *testing.B) {
+	for name, chat := range benchmarkChats(b) {
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(chat)))
+			for i := 0; i < b.N; i++ {
+				for _, err := range ParseReader(strings.NewReader(chat), nil) {
+					if err != nil {
+						b.Fatalf("Unexpected error: %v", err)
+					}
+				}
+			}
+		})
+	}
+}
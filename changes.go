@@ -0,0 +1,79 @@
+package parser
+
+import (
+	"strings"
+	"unicode"
+)
+
+// isSpaceOrMark reports whether r is a space or a direction mark
+func isSpaceOrMark(r rune) bool {
+	return unicode.IsSpace(r) || r == '\u200E' || r == '\u200F'
+}
+
+// deletionPlaceholder reports whether text is written in place of a deleted
+// message, and whether it says that the owner deleted it
+func deletionPlaceholder(text string, locales []*Locale) (deleted bool, byOwner bool) {
+	text = strings.TrimSuffix(trimMarks(text), ".")
+
+	for _, locale := range locales {
+		for _, placeholder := range locale.DeletedByOwner {
+			if text == strings.TrimSuffix(placeholder, ".") {
+				return true, true
+			}
+		}
+		for _, placeholder := range locale.Deleted {
+			if text == strings.TrimSuffix(placeholder, ".") {
+				return true, false
+			}
+		}
+	}
+	return false, false
+}
+
+// editedMarkerSpace is what separates the edit marker from the message
+const editedMarkerSpace = " \t\u00A0\u200E\u200F"
+
+// stripEditedMarker removes the marker ending an edited message
+func stripEditedMarker(text string, locales []*Locale) (string, bool) {
+	trimmed := strings.TrimRight(text, editedMarkerSpace)
+
+	for _, locale := range locales {
+		for _, marker := range locale.Edited {
+			if body, ok := strings.CutSuffix(trimmed, marker); ok {
+				return strings.TrimRight(body, editedMarkerSpace), true
+			}
+		}
+	}
+	return text, false
+}
+
+// markDeletedAndEdited sets the Deleted and Edited flags of a message and
+// strips their markers from its body. iOS writes a left-to-right mark before
+// the markers, which makes the messages look like system messages; they are
+// given back to their author.
+func (ctx *parseContext) markDeletedAndEdited(message *Message) {
+	text := message.Message
+	var author *string
+	if message.IsSystem {
+		if name, body, ok := strings.Cut(text, ": "); ok {
+			author = &name
+			text = body
+		}
+	}
+
+	if deleted, byOwner := deletionPlaceholder(text, ctx.grammar.locales); deleted {
+		message.Deleted = true
+		message.FromOwner = byOwner
+		message.Message = ""
+	} else if body, edited := stripEditedMarker(text, ctx.grammar.locales); edited && (!message.IsSystem || author != nil) {
+		message.Edited = true
+		message.Message = body
+	} else {
+		return
+	}
+
+	if author != nil {
+		message.Author = author
+		message.IsSystem = false
+	}
+}
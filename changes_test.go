@@ -0,0 +1,82 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestDeletedAndEdited tests the flags of deleted and edited messages
+func TestDeletedAndEdited(t *testing.T) {
+	content := "13/06/2018, 21:25 - Anna: This message was deleted\n" +
+		"13/06/2018, 21:26 - Anna: You deleted this message\n" +
+		"13/06/2018, 21:27 - Bob: see you at 8 <This message was edited>\n" +
+		"13/06/2018, 21:28 - Bob: Diese Nachricht wurde gelöscht\n" +
+		"13/06/2018, 21:29 - Bob: I said <This message was edited> before"
+
+	messages, err := ParseString(content, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		deleted, edited, fromOwner bool
+		message                    string
+	}{
+		{true, false, false, ""},
+		{true, false, true, ""},
+		{false, true, false, "see you at 8"},
+		{true, false, false, ""},
+		{false, false, false, "I said <This message was edited> before"},
+	}
+	for i, test := range tests {
+		message := messages[i]
+		if message.Deleted != test.deleted || message.Edited != test.edited || message.FromOwner != test.fromOwner || message.Message != test.message {
+			t.Errorf("Message %d: expected %+v, got %+v", i, test, message)
+		}
+	}
+
+	t.Run("iOS", func(t *testing.T) {
+		content := "[13/06/2018, 21:25:00] Anna: \u200EThis message was deleted.\n" +
+			"[13/06/2018, 21:26:00] Bob: see you \u200E<This message was edited>"
+
+		messages, err := ParseString(content, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		message := messages[0]
+		if message.IsSystem || message.Author == nil || *message.Author != "Anna" || !message.Deleted || message.Message != "" {
+			t.Errorf("Expected a deleted message of Anna, got %+v", message)
+		}
+		if !messages[1].Edited || messages[1].Message != "see you" {
+			t.Errorf("Expected an edited message, got %+v", messages[1])
+		}
+	})
+
+	t.Run("Format", func(t *testing.T) {
+		author := "Anna"
+		date := time.Date(2018, 6, 13, 21, 25, 0, 0, time.UTC)
+		messages := []Message{
+			{Date: date, WallClock: date, Author: &author, Deleted: true},
+			{Date: date, WallClock: date, Author: &author, Deleted: true, FromOwner: true},
+			{Date: date, WallClock: date, Author: &author, Message: "two\nlines\n", Edited: true},
+		}
+
+		for _, dialect := range []Dialect{DialectAndroid, DialectIOS, {Layout: LayoutDash, DaysFirst: true, Locale: "fr"}} {
+			content, err := Format(messages, dialect)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			daysFirst := true
+			parsed, err := ParseString(content, &ParseStringOptions{DaysFirst: &daysFirst})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(parsed, messages) {
+				t.Errorf("Expected the formatted chat to parse back to the same messages, got:\n%s", content)
+			}
+		}
+	})
+}
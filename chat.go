@@ -0,0 +1,102 @@
+package parser
+
+import (
+	"strings"
+	"time"
+)
+
+// Chat is a parsed chat with what is known about it as a whole
+type Chat struct {
+	Messages    []Message    `json:"messages"`
+	Diagnostics []Diagnostic `json:"diagnostics,omitempty"`
+	Format      *ChatFormat  `json:"format"`
+	// Participants are the authors of messages, in the order they first
+	// wrote
+	Participants []string   `json:"participants"`
+	FirstMessage *time.Time `json:"firstMessage"`
+	LastMessage  *time.Time `json:"lastMessage"`
+	MessageCount int        `json:"messageCount"`
+	// IsGroup is set for group chats, known from group events or from more
+	// than two participants
+	IsGroup bool `json:"isGroup"`
+	// Subject is the latest group name given by group events, or else the
+	// name iOS writes before the encryption notice of groups
+	Subject string `json:"subject,omitempty"`
+	// Owner is the name of the person who exported the chat, when a message
+	// tells it, such as "You deleted this message"
+	Owner *string `json:"owner,omitempty"`
+}
+
+// isGroupEvent reports whether an event only happens in group chats
+func isGroupEvent(eventType SystemEventType) bool {
+	switch eventType {
+	case SystemEventGroupCreated, SystemEventMemberAdded, SystemEventMemberRemoved, SystemEventMemberLeft,
+		SystemEventMemberJoined, SystemEventSubjectChanged, SystemEventIconChanged,
+		SystemEventDescriptionChanged, SystemEventAdminPromoted:
+		return true
+	}
+	return false
+}
+
+// NewChat gathers what the messages of a chat tell about it
+func NewChat(messages []Message) *Chat {
+	chat := &Chat{
+		Messages:     messages,
+		Participants: GetAuthorsFromMessages(&messages),
+		MessageCount: len(messages),
+	}
+	chat.FirstMessage, chat.LastMessage = GetFirstAndLastMessageDates(&messages)
+	chat.IsGroup = len(chat.Participants) > 2
+
+	var prefixSubject string
+	for _, message := range messages {
+		if message.FromOwner && message.Author != nil && chat.Owner == nil {
+			owner := *message.Author
+			chat.Owner = &owner
+		}
+
+		if !message.IsSystem {
+			continue
+		}
+		event := message.Event
+		if event == nil {
+			continue
+		}
+		// iOS writes the chat name before the encryption notice
+		if event.Type == SystemEventEncryption && prefixSubject == "" {
+			if prefix := regexSystemPrefix.FindString(message.Message); prefix != "" {
+				prefixSubject = strings.TrimSuffix(strings.TrimRightFunc(prefix, isSpaceOrMark), ":")
+			}
+		}
+		if !isGroupEvent(event.Type) {
+			continue
+		}
+		chat.IsGroup = true
+		if (event.Type == SystemEventGroupCreated || event.Type == SystemEventSubjectChanged) && event.Value != "" {
+			chat.Subject = event.Value
+		}
+	}
+
+	if chat.Subject == "" && chat.IsGroup {
+		chat.Subject = prefixSubject
+	}
+	return chat
+}
+
+// ParseChat parses a string containing a WhatsApp chat log into a Chat,
+// along with its format and the problems found. The format reports the
+// DaysFirst and Locale options when they are set.
+func ParseChat(content string, options *ParseStringOptions) (*Chat, error) {
+	result, err := Parse(content, options)
+	if err != nil {
+		return nil, err
+	}
+
+	chat := NewChat(result.Messages)
+	chat.Diagnostics = result.Diagnostics
+	if options == nil {
+		options = &ParseStringOptions{}
+	}
+	chat.Format = detectFormat(content, *options)
+	return chat, nil
+}
@@ -0,0 +1,97 @@
+package parser
+
+import (
+	"os"
+	"testing"
+)
+
+// TestParseChat tests the chat-level metadata
+func TestParseChat(t *testing.T) {
+	tests := []struct {
+		filePath     string
+		isGroup      bool
+		subject      string
+		participants int
+	}{
+		{"test_data/default.txt", true, "ShortChat", 3},
+		{"test_data/english_iphone-saved_contacts.txt", true, "Super Group", 3},
+		{"test_data/english_android-unsaved_contacts.txt", true, "", 0},
+	}
+
+	for _, test := range tests {
+		fileContents, err := os.ReadFile(test.filePath)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		chat, err := ParseChat(string(fileContents), nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if chat.IsGroup != test.isGroup || chat.Subject != test.subject {
+			t.Errorf("%s: expected group %v with subject %q, got %v %q", test.filePath, test.isGroup, test.subject, chat.IsGroup, chat.Subject)
+		}
+		if test.participants > 0 && len(chat.Participants) != test.participants {
+			t.Errorf("%s: expected %d participants, got %v", test.filePath, test.participants, chat.Participants)
+		}
+		if chat.MessageCount != len(chat.Messages) || chat.Format == nil || chat.FirstMessage == nil || chat.LastMessage == nil {
+			t.Errorf("%s: incomplete chat %+v", test.filePath, chat)
+		}
+	}
+
+	t.Run("One-to-one chat", func(t *testing.T) {
+		content := "13/06/2018, 21:25 - Anna: hi\n" +
+			"13/06/2018, 21:26 - Bob: You deleted this message\n" +
+			"13/06/2018, 21:27 - Anna: Anna changed the subject to \"Not a group\""
+
+		chat, err := ParseChat(content, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if chat.IsGroup || chat.Subject != "" || chat.MessageCount != 3 {
+			t.Errorf("Expected a one-to-one chat, got %+v", chat)
+		}
+		if chat.Owner == nil || *chat.Owner != "Bob" {
+			t.Errorf("Expected Bob as owner, got %v", chat.Owner)
+		}
+	})
+
+	t.Run("Subject changes", func(t *testing.T) {
+		content := "13/06/2018, 21:25 - Anna created group \"Trip\"\n" +
+			"13/06/2018, 21:26 - Anna changed the subject from \"Trip\" to \"Trip 2018\""
+
+		chat, err := ParseChat(content, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !chat.IsGroup || chat.Subject != "Trip 2018" || chat.Owner != nil {
+			t.Errorf("Expected group Trip 2018, got %+v", chat)
+		}
+	})
+
+	t.Run("Forced options", func(t *testing.T) {
+		content := "01/02/2018, 10:00 - Anna: hi\n" +
+			"01/02/2018, 10:05 - Bob: hello"
+
+		chat, err := ParseChat(content, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !chat.Format.DaysFirst || chat.Format.Locale != "" {
+			t.Errorf("Expected the detected day/month order and no locale, got %+v", chat.Format)
+		}
+
+		daysFirst := false
+		chat, err = ParseChat(content, &ParseStringOptions{DaysFirst: &daysFirst, Locale: "en"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if chat.Messages[0].Date.Month() != 1 || chat.Messages[0].Date.Day() != 2 {
+			t.Errorf("Expected the messages parsed month first, got %v", chat.Messages[0].Date)
+		}
+		if chat.Format.DaysFirst || chat.Format.Heuristic != HeuristicOption || chat.Format.Locale != "en" {
+			t.Errorf("Expected the format of the options, got %+v", chat.Format)
+		}
+	})
+}
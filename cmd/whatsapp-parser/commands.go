@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	parser "github.com/JanChodorowski/whatsapp-chat-parser-go"
+)
+
+// parseCommand prints the messages as a JSON array, or as NDJSON streamed
+// while a chat log is read
+func parseCommand(fs *flag.FlagSet) func(e *env, inputs []string) error {
+	format := fs.String("format", "json", "output format, json or ndjson")
+
+	return func(e *env, inputs []string) error {
+		switch *format {
+		case "json":
+			messages, err := e.messages(inputs)
+			if err != nil {
+				return err
+			}
+			if messages == nil {
+				messages = []parser.Message{}
+			}
+
+			encoder := json.NewEncoder(e.stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(messages)
+
+		case "ndjson":
+			encoder := json.NewEncoder(e.stdout)
+			return e.openInputs(inputs, func(in input) error {
+				for message, err := range e.stream(in) {
+					if err != nil {
+						return err
+					}
+					if err := encoder.Encode(message); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+
+		default:
+			return fmt.Errorf("%w: unknown format %q", errUsage, *format)
+		}
+	}
+}
+
+// authorsCommand prints the authors in the order they first wrote
+func authorsCommand(fs *flag.FlagSet) func(e *env, inputs []string) error {
+	return func(e *env, inputs []string) error {
+		messages, err := e.messages(inputs)
+		if err != nil {
+			return err
+		}
+
+		for _, author := range parser.GetAuthorsFromMessages(&messages) {
+			fmt.Fprintln(e.stdout, author)
+		}
+		return nil
+	}
+}
+
+// rangeCommand prints the dates of the first and last messages
+func rangeCommand(fs *flag.FlagSet) func(e *env, inputs []string) error {
+	return func(e *env, inputs []string) error {
+		messages, err := e.messages(inputs)
+		if err != nil {
+			return err
+		}
+
+		first, last := parser.GetFirstAndLastMessageDates(&messages)
+		if first == nil {
+			return nil
+		}
+		fmt.Fprintln(e.stdout, first.Format(time.RFC3339))
+		fmt.Fprintln(e.stdout, last.Format(time.RFC3339))
+		return nil
+	}
+}
+
+// statsCommand prints the statistics of the messages, overall and by author
+func statsCommand(fs *flag.FlagSet) func(e *env, inputs []string) error {
+	asJSON := fs.Bool("json", false, "print the statistics as JSON")
+
+	return func(e *env, inputs []string) error {
+		messages, err := e.messages(inputs)
+		if err != nil {
+			return err
+		}
+		stats := parser.GetStats(&messages)
+
+		if *asJSON {
+			encoder := json.NewEncoder(e.stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(stats)
+		}
+
+		authors := parser.GetAuthorsFromMessages(&messages)
+		sort.SliceStable(authors, func(i, j int) bool {
+			return stats.Authors[authors[i]].Messages > stats.Authors[authors[j]].Messages
+		})
+
+		w := tabwriter.NewWriter(e.stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(w, "author\tmessages\twords\tcharacters\tmedia\tlinks\temoji\tactive days\tlongest silence\t")
+		row := func(name string, s *parser.AuthorStats) {
+			media, emoji := 0, 0
+			for _, count := range s.Media {
+				media += count
+			}
+			for _, count := range s.Emoji {
+				emoji += count
+			}
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%s\t\n",
+				name, s.Messages, s.Words, s.Characters, media, s.Links, emoji, s.ActiveDays, s.LongestSilence)
+		}
+		for _, author := range authors {
+			row(author, stats.Authors[author])
+		}
+		row("total", &stats.Overall)
+		return w.Flush()
+	}
+}
+
+// validateCommand reports the problems found in each input, and fails if
+// there is any
+func validateCommand(fs *flag.FlagSet) func(e *env, inputs []string) error {
+	return func(e *env, inputs []string) error {
+		failed := false
+		err := e.openInputs(inputs, func(in input) error {
+			result, err := e.parse(in)
+			if err != nil {
+				fmt.Fprintln(e.stdout, err)
+				failed = true
+				return nil
+			}
+
+			for _, diagnostic := range result.Diagnostics {
+				fmt.Fprintf(e.stdout, "%s: %s\n", in.name, diagnostic)
+			}
+			fmt.Fprintf(e.stdout, "%s: %d messages, %d problems\n", in.name, len(result.Messages), len(result.Diagnostics))
+			failed = failed || len(result.Diagnostics) > 0
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if failed {
+			return errFailed
+		}
+		return nil
+	}
+}
+
+// anonymizeCommand prints the chat with its personal data replaced, in the
+// format of a chat log, and can save the mapping to the originals
+func anonymizeCommand(fs *flag.FlagSet) func(e *env, inputs []string) error {
+	keyFile := fs.String("key-file", "", "file holding the secret keying the pseudonyms")
+	mappingFile := fs.String("mapping", "", "file to save the mapping from the originals to the pseudonyms to, as JSON")
+	dialectName := fs.String("dialect", "android", "header format of the output, android or ios")
+
+	return func(e *env, inputs []string) error {
+		dialect, err := dialectByName(*dialectName)
+		if err != nil {
+			return err
+		}
+		options := &parser.AnonymizeOptions{}
+		if *keyFile != "" {
+			if options.Key, err = os.ReadFile(*keyFile); err != nil {
+				return err
+			}
+		}
+
+		messages, err := e.messages(inputs)
+		if err != nil {
+			return err
+		}
+		anonymized, mapping := parser.Anonymize(messages, options)
+
+		if *mappingFile != "" {
+			if mapping == nil {
+				mapping = []parser.Pseudonym{}
+			}
+			data, err := json.MarshalIndent(mapping, "", "  ")
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(*mappingFile, append(data, '\n'), 0o600); err != nil {
+				return err
+			}
+		}
+
+		if len(anonymized) == 0 {
+			return nil
+		}
+		if err := parser.Write(e.stdout, anonymized, dialect); err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(e.stdout)
+		return err
+	}
+}
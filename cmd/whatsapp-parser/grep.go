@@ -0,0 +1,258 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	parser "github.com/JanChodorowski/whatsapp-chat-parser-go"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// grepFlags are the flags of the grep command
+type grepFlags struct {
+	authors     string
+	from        string
+	to          string
+	system      bool
+	user        bool
+	attachment  string
+	weekdays    string
+	hours       string
+	ignoreCase  bool
+	invert      bool
+	format      string
+	dialectName string
+}
+
+// grepCommand prints the messages whose text matches a regular expression
+// and which pass the filters, and fails if there is none
+func grepCommand(fs *flag.FlagSet) func(e *env, inputs []string) error {
+	f := &grepFlags{}
+	fs.StringVar(&f.authors, "author", "", "comma-separated authors")
+	fs.StringVar(&f.from, "from", "", "first date, as 2006-01-02 or RFC 3339")
+	fs.StringVar(&f.to, "to", "", "date to stop before, as 2006-01-02 or RFC 3339")
+	fs.BoolVar(&f.system, "system", false, "only system messages")
+	fs.BoolVar(&f.user, "user", false, "only messages of participants")
+	fs.StringVar(&f.attachment, "attachment", "", `attachment kind, such as image, or "any"; needs -attachments`)
+	fs.StringVar(&f.weekdays, "weekday", "", "comma-separated days of the week, such as sat,sun")
+	fs.StringVar(&f.hours, "hour", "", "comma-separated hours or ranges, such as 9-12,14")
+	fs.BoolVar(&f.ignoreCase, "i", false, "ignore case in the pattern")
+	fs.BoolVar(&f.invert, "v", false, "print the messages not matching the pattern")
+	fs.StringVar(&f.format, "format", "text", "output format, text, json or ndjson")
+	fs.StringVar(&f.dialectName, "dialect", "android", "header format of text output, android or ios")
+
+	return func(e *env, args []string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("%w: missing pattern", errUsage)
+		}
+
+		filter, err := f.filter(args[0], e.options.Location)
+		if err != nil {
+			return fmt.Errorf("%w: %v", errUsage, err)
+		}
+
+		dialect, err := dialectByName(f.dialectName)
+		if err != nil {
+			return err
+		}
+
+		var matched []parser.Message
+		write := func(message parser.Message) error {
+			switch f.format {
+			case "text":
+				if err := parser.Write(e.stdout, []parser.Message{message}, dialect); err != nil {
+					return err
+				}
+				_, err := fmt.Fprintln(e.stdout)
+				return err
+			case "ndjson":
+				return json.NewEncoder(e.stdout).Encode(message)
+			}
+			matched = append(matched, message)
+			return nil
+		}
+		if f.format != "text" && f.format != "json" && f.format != "ndjson" {
+			return fmt.Errorf("%w: unknown format %q", errUsage, f.format)
+		}
+
+		found := false
+		err = e.openInputs(args[1:], func(in input) error {
+			for message, err := range filter.Stream(e.stream(in)) {
+				if err != nil {
+					return err
+				}
+				found = true
+				if err := write(message); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if f.format == "json" {
+			if matched == nil {
+				matched = []parser.Message{}
+			}
+			encoder := json.NewEncoder(e.stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(matched); err != nil {
+				return err
+			}
+		}
+		if !found {
+			return errFailed
+		}
+		return nil
+	}
+}
+
+// filter builds the filter selected by the flags and the pattern
+func (f *grepFlags) filter(pattern string, location *time.Location) (parser.Filter, error) {
+	if f.ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	matching := parser.Matching(re)
+	if f.invert {
+		matching = parser.Not(matching)
+	}
+	filters := []parser.Filter{matching}
+
+	if f.authors != "" {
+		filters = append(filters, parser.ByAuthors(splitList(f.authors)...))
+	}
+
+	if f.from != "" || f.to != "" {
+		from, err := parseDate(f.from, location)
+		if err != nil {
+			return nil, err
+		}
+		to, err := parseDate(f.to, location)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, parser.Between(from, to))
+	}
+
+	if f.system && f.user {
+		return nil, fmt.Errorf("-system and -user exclude each other")
+	}
+	if f.system {
+		filters = append(filters, parser.SystemMessages())
+	}
+	if f.user {
+		filters = append(filters, parser.UserMessages())
+	}
+
+	switch f.attachment {
+	case "":
+	case "any":
+		filters = append(filters, parser.WithAttachment())
+	default:
+		filters = append(filters, parser.WithAttachment(parser.AttachmentKind(f.attachment)))
+	}
+
+	if f.weekdays != "" {
+		var days []time.Weekday
+		for _, name := range splitList(f.weekdays) {
+			day, ok := weekdays[strings.ToLower(name[:min(3, len(name))])]
+			if !ok {
+				return nil, fmt.Errorf("unknown day of the week %q", name)
+			}
+			days = append(days, day)
+		}
+		filters = append(filters, parser.OnWeekdays(days...))
+	}
+
+	if f.hours != "" {
+		hours, err := parseHours(f.hours)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, parser.AtHours(hours...))
+	}
+
+	return parser.And(filters...), nil
+}
+
+// dialectByName returns the dialect of the -dialect flag
+func dialectByName(name string) (parser.Dialect, error) {
+	switch name {
+	case "android":
+		return parser.DialectAndroid, nil
+	case "ios":
+		return parser.DialectIOS, nil
+	}
+	return parser.Dialect{}, fmt.Errorf("%w: unknown dialect %q", errUsage, name)
+}
+
+// splitList splits a comma-separated list, dropping spaces around the items
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseDate parses a date flag, in the parse location when it has no offset
+func parseDate(value string, location *time.Location) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if location == nil {
+		location = time.UTC
+	}
+	if date, err := time.ParseInLocation("2006-01-02", value, location); err == nil {
+		return date, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// parseHours parses a list of hours and ranges of hours, like "9-12,14"
+func parseHours(list string) ([]int, error) {
+	var hours []int
+	for _, item := range splitList(list) {
+		first, last, isRange := strings.Cut(item, "-")
+		from, err := strconv.Atoi(first)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hour %q", item)
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.Atoi(last); err != nil {
+				return nil, fmt.Errorf("invalid hour %q", item)
+			}
+		}
+		if from < 0 || to > 23 || from > to {
+			return nil, fmt.Errorf("invalid hour %q", item)
+		}
+		for hour := from; hour <= to; hour++ {
+			hours = append(hours, hour)
+		}
+	}
+	return hours, nil
+}
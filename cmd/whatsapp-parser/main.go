@@ -0,0 +1,311 @@
+// Command whatsapp-parser parses WhatsApp chat exports from the command line.
+//
+// Usage:
+//
+//	whatsapp-parser <command> [flags] [file ...]
+//
+// Each file is a chat log or an "Export chat" zip archive; with no file, or
+// with "-", the chat is read from standard input. The commands are:
+//
+//	parse     print the messages as JSON or NDJSON
+//	authors   print the authors, one per line
+//	range     print the dates of the first and last messages
+//	stats     print the statistics of the messages by author
+//	validate  report the problems found while parsing
+//	grep      print the messages matching a pattern and filters
+//	anonymize print the chat with names, numbers and addresses replaced
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"strconv"
+	"time"
+
+	parser "github.com/JanChodorowski/whatsapp-chat-parser-go"
+)
+
+const usage = `usage: whatsapp-parser <command> [flags] [file ...]
+
+Commands:
+  parse     print the messages as JSON or NDJSON
+  authors   print the authors, one per line
+  range     print the dates of the first and last messages
+  stats     print the statistics of the messages by author
+  validate  report the problems found while parsing
+  grep      print the messages matching a pattern and filters
+  anonymize print the chat with names, numbers and addresses replaced
+
+Files are chat logs or "Export chat" zip archives. With no file, or with "-",
+the chat is read from standard input. Run "whatsapp-parser <command> -h" for
+the flags of a command.
+`
+
+// errUsage is returned by commands given bad flags or arguments
+var errUsage = errors.New("usage error")
+
+// errFailed is returned by commands that already reported the failure
+var errFailed = errors.New("failed")
+
+// command is a subcommand. setup defines its own flags and returns the
+// function running it once the flags are parsed.
+type command struct {
+	name  string
+	setup func(fs *flag.FlagSet) func(e *env, inputs []string) error
+}
+
+var commands = []command{
+	{"parse", parseCommand},
+	{"authors", authorsCommand},
+	{"range", rangeCommand},
+	{"stats", statsCommand},
+	{"validate", validateCommand},
+	{"grep", grepCommand},
+	{"anonymize", anonymizeCommand},
+}
+
+// env holds the standard streams and parse options of a run
+type env struct {
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+	options *parser.ParseStringOptions
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command line given in args and returns the exit status
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+		fmt.Fprint(stderr, usage)
+		if len(args) == 0 {
+			return 2
+		}
+		return 0
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+
+		fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+		fs.SetOutput(stderr)
+		optionFlags := addOptionFlags(fs)
+		runCommand := cmd.setup(fs)
+		if err := fs.Parse(args[1:]); err != nil {
+			if err == flag.ErrHelp {
+				return 0
+			}
+			return 2
+		}
+
+		options, err := optionFlags.options()
+		if err != nil {
+			fmt.Fprintf(stderr, "whatsapp-parser: %v\n", err)
+			return 2
+		}
+
+		e := &env{stdin: stdin, stdout: stdout, stderr: stderr, options: options}
+		switch err := runCommand(e, fs.Args()); {
+		case err == nil:
+			return 0
+		case errors.Is(err, errUsage):
+			fmt.Fprintf(stderr, "whatsapp-parser %s: %v\n", cmd.name, err)
+			return 2
+		case errors.Is(err, errFailed):
+			return 1
+		default:
+			fmt.Fprintf(stderr, "whatsapp-parser %s: %v\n", cmd.name, err)
+			return 1
+		}
+	}
+
+	fmt.Fprintf(stderr, "whatsapp-parser: unknown command %q\n\n%s", args[0], usage)
+	return 2
+}
+
+// optionalBool is a boolean flag that can be left unset
+type optionalBool struct {
+	value *bool
+}
+
+func (b *optionalBool) String() string {
+	if b == nil || b.value == nil {
+		return ""
+	}
+	return strconv.FormatBool(*b.value)
+}
+
+func (b *optionalBool) Set(s string) error {
+	value, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	b.value = &value
+	return nil
+}
+
+func (b *optionalBool) IsBoolFlag() bool {
+	return true
+}
+
+// optionFlags are the flags setting the fields of ParseStringOptions
+type optionFlags struct {
+	daysFirst   optionalBool
+	attachments bool
+	lookAhead   int
+	locale      string
+	strict      bool
+	location    string
+	concurrency int
+}
+
+// addOptionFlags defines the parse option flags shared by every command
+func addOptionFlags(fs *flag.FlagSet) *optionFlags {
+	f := &optionFlags{}
+	fs.Var(&f.daysFirst, "days-first", "dates have the day before the month (detected when unset)")
+	fs.BoolVar(&f.attachments, "attachments", false, "parse attachments")
+	fs.IntVar(&f.lookAhead, "look-ahead", 0, "messages read ahead to detect the date order when streaming, -1 for the whole chat")
+	fs.StringVar(&f.locale, "locale", "", "locale of the AM/PM markers and system messages (all locales when empty)")
+	fs.BoolVar(&f.strict, "strict", false, "fail instead of guessing")
+	fs.StringVar(&f.location, "location", "", "time zone of the dates, such as Europe/Warsaw (UTC when empty)")
+	fs.IntVar(&f.concurrency, "concurrency", 0, "goroutines building messages when not streaming, -1 for one per CPU")
+	return f
+}
+
+// options returns the parse options set by the flags
+func (f *optionFlags) options() (*parser.ParseStringOptions, error) {
+	options := &parser.ParseStringOptions{
+		DaysFirst:        f.daysFirst.value,
+		ParseAttachments: f.attachments,
+		LookAhead:        f.lookAhead,
+		Locale:           f.locale,
+		Strict:           f.strict,
+		Concurrency:      f.concurrency,
+	}
+
+	if f.location != "" {
+		location, err := time.LoadLocation(f.location)
+		if err != nil {
+			return nil, err
+		}
+		options.Location = location
+	}
+	return options, nil
+}
+
+// zipSignature starts every zip archive
+var zipSignature = []byte("PK\x03\x04")
+
+// input is a chat log or archive to parse
+type input struct {
+	name string
+	r    *bufio.Reader
+	zip  bool
+}
+
+// openInputs opens the named inputs in turn, standard input standing for
+// "-" or for no name at all, and calls fn with each until it fails
+func (e *env) openInputs(names []string, fn func(in input) error) error {
+	if len(names) == 0 {
+		names = []string{"-"}
+	}
+
+	for _, name := range names {
+		err := func() error {
+			var r io.Reader = e.stdin
+			if name != "-" {
+				f, err := os.Open(name)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				r = f
+			}
+
+			in := input{name: name, r: bufio.NewReader(r)}
+			if name == "-" {
+				in.name = "<stdin>"
+			}
+			signature, _ := in.r.Peek(len(zipSignature))
+			in.zip = bytes.Equal(signature, zipSignature)
+			return fn(in)
+		}()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parse reads and parses a whole input
+func (e *env) parse(in input) (*parser.ParseResult, error) {
+	content, err := io.ReadAll(in.r)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *parser.ParseResult
+	if in.zip {
+		result, err = parser.ParseArchive(bytes.NewReader(content), int64(len(content)), e.options)
+	} else {
+		result, err = parser.Parse(string(content), e.options)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", in.name, err)
+	}
+	return result, nil
+}
+
+// stream parses an input, yielding each message as soon as it is read from a
+// chat log. Archives are read whole first.
+func (e *env) stream(in input) iter.Seq2[parser.Message, error] {
+	if !in.zip {
+		return func(yield func(parser.Message, error) bool) {
+			for message, err := range parser.ParseReader(in.r, e.options) {
+				if err != nil {
+					err = fmt.Errorf("%s: %w", in.name, err)
+				}
+				if !yield(message, err) {
+					return
+				}
+			}
+		}
+	}
+
+	return func(yield func(parser.Message, error) bool) {
+		result, err := e.parse(in)
+		if err != nil {
+			yield(parser.Message{}, err)
+			return
+		}
+		for _, message := range result.Messages {
+			if !yield(message, nil) {
+				return
+			}
+		}
+	}
+}
+
+// messages parses the named inputs and returns their messages in turn
+func (e *env) messages(names []string) ([]parser.Message, error) {
+	var messages []parser.Message
+	err := e.openInputs(names, func(in input) error {
+		result, err := e.parse(in)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, result.Messages...)
+		return nil
+	})
+	return messages, err
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	parser "github.com/JanChodorowski/whatsapp-chat-parser-go"
+)
+
+const chat = "13/06/2018, 21:25 - Anna: hello\n" +
+	"13/06/2018, 21:26 - Bob: <attached: photo.jpg>\n" +
+	"14/06/2018, 08:00 - Anna: morning"
+
+// runCommand runs a command line reading stdin, and returns its exit status
+// and output
+func runCommand(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	status := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return status, stdout.String(), stderr.String()
+}
+
+// TestRun tests the commands of the tool
+func TestRun(t *testing.T) {
+	t.Run("parse", func(t *testing.T) {
+		status, stdout, stderr := runCommand(t, chat, "parse", "-attachments")
+		if status != 0 {
+			t.Fatalf("Expected status 0, got %d: %s", status, stderr)
+		}
+
+		var messages []parser.Message
+		if err := json.Unmarshal([]byte(stdout), &messages); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(messages) != 3 || messages[1].Attachment == nil || messages[1].Attachment.FileName != "photo.jpg" {
+			t.Errorf("Expected 3 messages with an attachment, got %+v", messages)
+		}
+	})
+
+	t.Run("parse ndjson", func(t *testing.T) {
+		status, stdout, _ := runCommand(t, chat, "parse", "-format", "ndjson", "-days-first=false", "-look-ahead", "-1")
+		if status != 0 {
+			t.Fatalf("Expected status 0, got %d", status)
+		}
+
+		lines := strings.Split(strings.TrimSpace(stdout), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("Expected 3 lines, got %d", len(lines))
+		}
+		var message parser.Message
+		if err := json.Unmarshal([]byte(lines[0]), &message); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		// 13/06 is not a valid month/day date
+		if message.Date.Year() == 2018 && message.Date.Month() == 6 {
+			t.Errorf("Expected the forced month/day order, got %v", message.Date)
+		}
+	})
+
+	t.Run("authors and range", func(t *testing.T) {
+		_, stdout, _ := runCommand(t, chat, "authors")
+		if stdout != "Anna\nBob\n" {
+			t.Errorf("Expected Anna and Bob, got %q", stdout)
+		}
+
+		_, stdout, _ = runCommand(t, chat, "range", "-location", "Europe/Warsaw")
+		if stdout != "2018-06-13T21:25:00+02:00\n2018-06-14T08:00:00+02:00\n" {
+			t.Errorf("Unexpected range %q", stdout)
+		}
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		_, stdout, _ := runCommand(t, chat, "stats", "-attachments")
+		lines := strings.Split(stdout, "\n")
+		if len(lines) != 5 || !strings.HasPrefix(strings.TrimSpace(lines[1]), "Anna  ") || !strings.Contains(lines[3], "total") {
+			t.Errorf("Expected a row by author and a total, got %q", stdout)
+		}
+
+		_, stdout, _ = runCommand(t, chat, "stats", "-attachments", "-json")
+		var stats parser.Stats
+		if err := json.Unmarshal([]byte(stdout), &stats); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if stats.Overall.Messages != 3 || stats.Authors["Bob"].Media[parser.AttachmentImage] != 1 {
+			t.Errorf("Unexpected stats %+v", stats)
+		}
+	})
+
+	t.Run("validate", func(t *testing.T) {
+		status, stdout, _ := runCommand(t, chat, "validate")
+		if status != 0 || stdout != "<stdin>: 3 messages, 0 problems\n" {
+			t.Errorf("Expected no problems, got %d %q", status, stdout)
+		}
+
+		status, stdout, _ = runCommand(t, "stray\n"+chat, "validate")
+		if status != 1 || !strings.Contains(stdout, "line 1:") {
+			t.Errorf("Expected a dropped line problem, got %d %q", status, stdout)
+		}
+
+		status, _, _ = runCommand(t, "stray\n"+chat, "validate", "-strict")
+		if status != 1 {
+			t.Errorf("Expected strict validation to fail, got %d", status)
+		}
+	})
+
+	t.Run("grep", func(t *testing.T) {
+		status, stdout, _ := runCommand(t, chat, "grep", "-i", "HELLO")
+		if status != 0 || stdout != "13/06/2018, 21:25 - Anna: hello\n" {
+			t.Errorf("Expected the matching message, got %d %q", status, stdout)
+		}
+
+		status, stdout, _ = runCommand(t, chat, "grep", "-author", "Anna", "-from", "2018-06-14", "-dialect", "ios", "")
+		if status != 0 || stdout != "[14/06/2018, 08:00:00] Anna: morning\n" {
+			t.Errorf("Expected the morning message, got %d %q", status, stdout)
+		}
+
+		status, stdout, _ = runCommand(t, chat, "grep", "-attachments", "-attachment", "image", "-hour", "20-22", "-weekday", "wed", "-format", "ndjson", "")
+		if status != 0 || strings.Count(stdout, "\n") != 1 || !strings.Contains(stdout, "photo.jpg") {
+			t.Errorf("Expected the photo, got %d %q", status, stdout)
+		}
+
+		if status, _, _ := runCommand(t, chat, "grep", "-v", ""); status != 1 {
+			t.Errorf("Expected status 1 without matches, got %d", status)
+		}
+		if status, _, _ := runCommand(t, chat, "grep", "-hour", "25", ""); status != 2 {
+			t.Errorf("Expected status 2 for an invalid hour, got %d", status)
+		}
+	})
+
+	t.Run("anonymize", func(t *testing.T) {
+		dir := t.TempDir()
+		key := filepath.Join(dir, "key")
+		mapping := filepath.Join(dir, "mapping.json")
+		if err := os.WriteFile(key, []byte("secret"), 0o600); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		status, stdout, stderr := runCommand(t, chat, "anonymize", "-attachments", "-key-file", key, "-mapping", mapping)
+		if status != 0 {
+			t.Fatalf("Expected status 0, got %d: %s", status, stderr)
+		}
+		if strings.Contains(stdout, "Anna") || strings.Contains(stdout, "photo.jpg") || strings.Count(stdout, "\n") != 3 {
+			t.Errorf("Expected the chat anonymized, got %q", stdout)
+		}
+
+		data, err := os.ReadFile(mapping)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var pseudonyms []parser.Pseudonym
+		if err := json.Unmarshal(data, &pseudonyms); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(pseudonyms) != 3 || !strings.Contains(stdout, pseudonyms[1].Pseudonym+": hello") {
+			t.Errorf("Expected the mapping of Anna, Bob and the photo, got %+v", pseudonyms)
+		}
+	})
+
+	t.Run("zip archives", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := zip.NewWriter(&buf)
+		for name, content := range map[string]string{"_chat.txt": chat, "photo.jpg": "jpeg"} {
+			f, err := w.Create(name)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			f.Write([]byte(content))
+		}
+		w.Close()
+
+		name := filepath.Join(t.TempDir(), "export.zip")
+		if err := os.WriteFile(name, buf.Bytes(), 0o644); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		status, stdout, stderr := runCommand(t, "", "parse", "-format", "ndjson", "-attachments", name)
+		if status != 0 {
+			t.Fatalf("Expected status 0, got %d: %s", status, stderr)
+		}
+		if !strings.Contains(stdout, `"path":"photo.jpg","size":4`) {
+			t.Errorf("Expected the attachment linked to the archive, got %q", stdout)
+		}
+	})
+
+	t.Run("usage errors", func(t *testing.T) {
+		tests := [][]string{
+			{},
+			{"unknown"},
+			{"parse", "-format", "xml"},
+			{"parse", "-days-first=maybe"},
+			{"parse", "-location", "Nowhere/City"},
+		}
+
+		for _, args := range tests {
+			if status, _, _ := runCommand(t, chat, args...); status != 2 {
+				t.Errorf("Expected status 2 for %v, got %d", args, status)
+			}
+		}
+
+		if status, _, _ := runCommand(t, "", "authors", "missing.txt"); status != 1 {
+			t.Errorf("Expected status 1 for a missing file, got %d", status)
+		}
+	})
+}
@@ -1,6 +1,11 @@
 package parser
 
-import "regexp"
+import (
+	"regexp"
+	"time"
+)
+
+var regexDateSeparator = regexp.MustCompile(`[-/.] ?`)
 
 // checkAbove12 checks if days come before months in dates by looking for numbers > 12
 func checkAbove12(numericDates [][]int) *bool {
@@ -114,6 +119,118 @@ func daysBeforeMonths(numericDates [][]int) *bool {
 	return changeFrequencyAnalysis(numericDates)
 }
 
+// explainDateOrder returns what daysBeforeMonths returns along with the check
+// that decided it and the number of observations supporting the decision
+func explainDateOrder(numericDates [][]int) (*bool, DateOrderHeuristic, int) {
+	if result := checkAbove12(numericDates); result != nil {
+		index := 1
+		if *result {
+			index = 0
+		}
+
+		evidence := 0
+		for _, date := range numericDates {
+			if indexAboveValue(index, 12)(date) {
+				evidence++
+			}
+		}
+		return result, HeuristicAbove12, evidence
+	}
+
+	if result := checkDecreasing(numericDates); result != nil {
+		index := 1
+		if *result {
+			index = 0
+		}
+
+		evidence := 0
+		for _, dates := range groupArrayByValueAtIndex(numericDates, 2) {
+			for i := 1; i < len(dates); i++ {
+				if isNegative(dates[i][index] - dates[i-1][index]) {
+					evidence++
+				}
+			}
+		}
+		return result, HeuristicDecreasing, evidence
+	}
+
+	if result := changeFrequencyAnalysis(numericDates); result != nil {
+		first, second := 0, 0
+		for i := 1; i < len(numericDates); i++ {
+			first += abs(numericDates[i][0] - numericDates[i-1][0])
+			second += abs(numericDates[i][1] - numericDates[i-1][1])
+		}
+		return result, HeuristicChangeFrequency, abs(first - second)
+	}
+
+	return nil, HeuristicDefault, 0
+}
+
+// dateOrderTracker accumulates the evidence used by daysBeforeMonths one date
+// at a time, so the same answer can be computed without keeping every date.
+// Its fields are exported to be saved in a ParserState.
+type dateOrderTracker struct {
+	Count            int           `json:"count"`
+	FirstAbove12     bool          `json:"firstAbove12,omitempty"`
+	SecondAbove12    bool          `json:"secondAbove12,omitempty"`
+	FirstDecreasing  bool          `json:"firstDecreasing,omitempty"`
+	SecondDecreasing bool          `json:"secondDecreasing,omitempty"`
+	LastByYear       map[int][]int `json:"lastByYear,omitempty"`
+	Previous         []int         `json:"previous,omitempty"`
+	FirstChange      int           `json:"firstChange"`
+	SecondChange     int           `json:"secondChange"`
+}
+
+// add records the date components of one more message
+func (t *dateOrderTracker) add(date []int) {
+	if date[0] > 12 {
+		t.FirstAbove12 = true
+	}
+	if date[1] > 12 {
+		t.SecondAbove12 = true
+	}
+
+	if t.LastByYear == nil {
+		t.LastByYear = make(map[int][]int)
+	}
+	if last, ok := t.LastByYear[date[2]]; ok {
+		if isNegative(date[0] - last[0]) {
+			t.FirstDecreasing = true
+		}
+		if isNegative(date[1] - last[1]) {
+			t.SecondDecreasing = true
+		}
+	}
+	t.LastByYear[date[2]] = date
+
+	if t.Previous != nil {
+		t.FirstChange += abs(date[0] - t.Previous[0])
+		t.SecondChange += abs(date[1] - t.Previous[1])
+	}
+	t.Previous = date
+	t.Count++
+}
+
+// result returns what daysBeforeMonths would return for all the dates added so far
+func (t *dateOrderTracker) result() *bool {
+	var result bool
+	switch {
+	case t.FirstAbove12:
+		result = true
+	case t.SecondAbove12:
+		result = false
+	case t.FirstDecreasing:
+		result = true
+	case t.SecondDecreasing:
+		result = false
+	case t.Count <= 1 || t.FirstChange == t.SecondChange:
+		return nil
+	default:
+		result = t.FirstChange > t.SecondChange
+	}
+	return &result
+}
+
 // normalizeDate takes year, month, and day as strings and pads them
 func normalizeDate(year, month, day string) [3]string {
 	// 2-digit years are assumed to be in the 2000-2099 range
@@ -135,8 +252,7 @@ func normalizeDate(year, month, day string) [3]string {
 
 // orderDateComponents pushes the longest number to the end (assumed to be the year)
 func orderDateComponents(date string) [3]string {
-	re := regexp.MustCompile(`[-/.] ?`)
-	parts := re.Split(date, -1)
+	parts := regexDateSeparator.Split(date, -1)
 
 	a, b, c := parts[0], parts[1], parts[2]
 	maxLength := max(len(a), max(len(b), len(c)))
@@ -149,3 +265,12 @@ func orderDateComponents(date string) [3]string {
 	}
 	return [3]string{b, c, a}
 }
+
+// isValidDate checks that the month and day exist in the calendar
+func isValidDate(year, month, day int) bool {
+	if month < 1 || month > 12 || day < 1 {
+		return false
+	}
+	daysInMonth := time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	return day <= daysInMonth
+}
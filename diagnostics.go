@@ -0,0 +1,59 @@
+package parser
+
+import "fmt"
+
+func (d Diagnostic) String() string {
+	if d.Line == 0 {
+		return d.Message
+	}
+	return fmt.Sprintf("line %d: %s", d.Line, d.Message)
+}
+
+// ParseError is returned in strict mode for the first problem that would
+// otherwise have been reported as a diagnostic and guessed around
+type ParseError struct {
+	Diagnostic
+}
+
+func (e *ParseError) Error() string {
+	return "parser: " + e.Diagnostic.String()
+}
+
+// isFatal reports whether a diagnostic fails the parse in strict mode
+func (d Diagnostic) isFatal() bool {
+	switch d.Kind {
+	case DiagnosticDroppedLine, DiagnosticInvalidDate, DiagnosticInvalidTime, DiagnosticUndetectedDateOrder:
+		return true
+	}
+	return false
+}
+
+// report records a diagnostic of the parse, and in strict mode turns the
+// first fatal one into the error of the parse
+func (ctx *parseContext) report(d Diagnostic) {
+	ctx.diagnostics = append(ctx.diagnostics, d)
+	if ctx.options.Strict && ctx.err == nil && d.isFatal() {
+		ctx.err = &ParseError{Diagnostic: d}
+	}
+}
+
+// reportAmbiguousDateOrder records that no date had a day or month above 12,
+// so the day/month order was guessed, or defaulted when nothing was detected
+func (ctx *parseContext) reportAmbiguousDateOrder(daysFirst bool, detected bool) {
+	order := "month/day"
+	if daysFirst {
+		order = "day/month"
+	}
+
+	if !detected {
+		ctx.report(Diagnostic{
+			Kind:    DiagnosticUndetectedDateOrder,
+			Message: "date order could not be detected, assumed " + order + " order",
+		})
+		return
+	}
+	ctx.report(Diagnostic{
+		Kind:    DiagnosticAmbiguousDateOrder,
+		Message: "no date has a day above 12, guessed " + order + " order",
+	})
+}
@@ -0,0 +1,114 @@
+package parser
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// SystemEventPattern recognises one kind of system message. The pattern is
+// matched against the whole message text and may capture the named groups
+// actor, targets, value and previous. Value is used when the pattern has no
+// value group.
+type SystemEventPattern struct {
+	Type    SystemEventType
+	Pattern *regexp.Regexp
+	Value   string
+}
+
+// regexSystemPrefix matches the "Group name: " prefix iOS puts before the
+// left-to-right mark of a system message
+var regexSystemPrefix = regexp.MustCompile(`^[^\x{200E}]*?:\s\x{200E}`)
+
+// systemEventText strips the iOS prefix and the direction marks around the
+// text of a system message, and returns the offset of the text in it
+func systemEventText(message string) (string, int) {
+	offset := 0
+	if loc := regexSystemPrefix.FindStringIndex(message); loc != nil {
+		offset = loc[1]
+	}
+	text := strings.TrimRight(message[offset:], " \u200E\u200F")
+	trimmed := strings.TrimLeft(text, " \u200E\u200F")
+	return trimmed, offset + len(text) - len(trimmed)
+}
+
+// splitEventTargets splits a list of names such as "Anna, Bob and you", and
+// returns the span of each name in the list
+func splitEventTargets(targets string, separator *regexp.Regexp) ([]string, [][2]int) {
+	var names []string
+	var spans [][2]int
+	start := 0
+	add := func(end int) {
+		target := targets[start:end]
+		trimmed := strings.TrimLeftFunc(target, unicode.IsSpace)
+		from := start + len(target) - len(trimmed)
+		if trimmed = strings.TrimRightFunc(trimmed, unicode.IsSpace); trimmed != "" {
+			names = append(names, trimmed)
+			spans = append(spans, [2]int{from, from + len(trimmed)})
+		}
+	}
+	for _, loc := range separator.FindAllStringIndex(targets, -1) {
+		add(loc[0])
+		start = loc[1]
+	}
+	add(len(targets))
+	return names, spans
+}
+
+// parseSystemEvent recognises the event reported by a system message with
+// the patterns of the given locales, and returns nil if none match
+func parseSystemEvent(message string, locales []*Locale) *SystemEvent {
+	event, _ := findSystemEvent(message, locales)
+	return event
+}
+
+// findSystemEvent is parseSystemEvent also returning the spans of the actor
+// and targets in the message, in order
+func findSystemEvent(message string, locales []*Locale) (*SystemEvent, [][2]int) {
+	text, offset := systemEventText(message)
+
+	for _, locale := range locales {
+		for _, p := range locale.SystemEvents {
+			matches := p.Pattern.FindStringSubmatchIndex(text)
+			if matches == nil {
+				continue
+			}
+
+			event := SystemEvent{
+				Type:  p.Type,
+				Value: p.Value,
+			}
+			var spans [][2]int
+			for i, name := range p.Pattern.SubexpNames() {
+				start, end := matches[2*i], matches[2*i+1]
+				value := ""
+				if start >= 0 {
+					value = text[start:end]
+				}
+				switch name {
+				case "actor":
+					event.Actor = &value
+					if start >= 0 {
+						spans = append(spans, [2]int{offset + start, offset + end})
+					}
+				case "targets":
+					var targetSpans [][2]int
+					event.Targets, targetSpans = splitEventTargets(value, locale.listSeparator)
+					for _, span := range targetSpans {
+						spans = append(spans, [2]int{offset + start + span[0], offset + start + span[1]})
+					}
+				case "value":
+					event.Value = value
+				case "previous":
+					event.Previous = value
+				}
+			}
+
+			sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+			return &event, spans
+		}
+	}
+
+	return nil, nil
+}
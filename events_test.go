@@ -0,0 +1,67 @@
+package parser
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+// TestSystemEvents tests system message classification
+func TestSystemEvents(t *testing.T) {
+	t.Run("IsSystem set from test data", func(t *testing.T) {
+		fileContents, err := os.ReadFile("test_data/english_iphone-saved_contacts.txt")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		messages, err := ParseString(string(fileContents), nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		for i, message := range messages {
+			if message.IsSystem != (i < 3) {
+				t.Errorf("Expected IsSystem to be %v for %q", i < 3, message.Message)
+			}
+			if message.IsSystem != (message.Author == nil) {
+				t.Errorf("Expected system messages to have no author, got %v for %q", message.Author, message.Message)
+			}
+		}
+
+		expected := []SystemEventType{SystemEventEncryption, SystemEventGroupCreated, SystemEventMemberAdded}
+		for i, eventType := range expected {
+			if messages[i].Event == nil || messages[i].Event.Type != eventType {
+				t.Errorf("Expected event %q for %q, got %+v", eventType, messages[i].Message, messages[i].Event)
+			}
+		}
+	})
+
+	t.Run("Parse events", func(t *testing.T) {
+		actor := func(name string) *string { return &name }
+		tests := []struct {
+			message string
+			expect  *SystemEvent
+		}{
+			{`You created group "ShortChat"`, &SystemEvent{Type: SystemEventGroupCreated, Actor: actor("You"), Value: "ShortChat"}},
+			{"Super Group: \u200EJosh added Anna, Bob and you", &SystemEvent{Type: SystemEventMemberAdded, Actor: actor("Josh"), Targets: []string{"Anna", "Bob", "you"}}},
+			{"Josh removed +48 777 666 555", &SystemEvent{Type: SystemEventMemberRemoved, Actor: actor("Josh"), Targets: []string{"+48 777 666 555"}}},
+			{"Anna left", &SystemEvent{Type: SystemEventMemberLeft, Actor: actor("Anna")}},
+			{"Anna joined using this group's invite link", &SystemEvent{Type: SystemEventMemberJoined, Actor: actor("Anna")}},
+			{`Josh changed the subject from "Old" to "New"`, &SystemEvent{Type: SystemEventSubjectChanged, Actor: actor("Josh"), Value: "New", Previous: "Old"}},
+			{"Josh changed this group's icon", &SystemEvent{Type: SystemEventIconChanged, Actor: actor("Josh"), Value: "changed"}},
+			{"Josh changed the group description", &SystemEvent{Type: SystemEventDescriptionChanged, Actor: actor("Josh"), Value: "changed"}},
+			{"+33 6 99 88 77 66 changed to +33 6 11 22 33 44", &SystemEvent{Type: SystemEventNumberChanged, Actor: actor("+33 6 99 88 77 66"), Value: "+33 6 11 22 33 44"}},
+			{"Your security code with Anna changed. Tap to learn more.", &SystemEvent{Type: SystemEventSecurityCodeChanged, Targets: []string{"Anna"}}},
+			{"Josh turned on disappearing messages. Tap to change.", &SystemEvent{Type: SystemEventDisappearingToggled, Actor: actor("Josh"), Value: "on"}},
+			{"You're now an admin", &SystemEvent{Type: SystemEventAdminPromoted, Targets: []string{"You"}}},
+			{"Something else happened", nil},
+		}
+
+		for _, test := range tests {
+			result := parseSystemEvent(test.message, []*Locale{LookupLocale("en")})
+			if !reflect.DeepEqual(result, test.expect) {
+				t.Errorf("parseSystemEvent(%q) = %+v, want %+v", test.message, result, test.expect)
+			}
+		}
+	})
+}
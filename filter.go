@@ -0,0 +1,130 @@
+package parser
+
+import (
+	"iter"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// Filter reports whether a message is kept. Filters are combined with And,
+// Or and Not, and applied to slices with Apply and to streams with Stream.
+type Filter func(message Message) bool
+
+// And keeps the messages kept by every filter, and all messages when there
+// is none
+func And(filters ...Filter) Filter {
+	return func(message Message) bool {
+		for _, filter := range filters {
+			if !filter(message) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or keeps the messages kept by any of the filters
+func Or(filters ...Filter) Filter {
+	return func(message Message) bool {
+		for _, filter := range filters {
+			if filter(message) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not keeps the messages the filter drops
+func Not(filter Filter) Filter {
+	return func(message Message) bool {
+		return !filter(message)
+	}
+}
+
+// ByAuthors keeps the messages written by one of the authors
+func ByAuthors(authors ...string) Filter {
+	return func(message Message) bool {
+		return message.Author != nil && slices.Contains(authors, *message.Author)
+	}
+}
+
+// Between keeps the messages sent from the first time on and before the
+// second. A zero time leaves that side open.
+func Between(from, to time.Time) Filter {
+	return func(message Message) bool {
+		return (from.IsZero() || !message.Date.Before(from)) && (to.IsZero() || message.Date.Before(to))
+	}
+}
+
+// SystemMessages keeps the system messages
+func SystemMessages() Filter {
+	return func(message Message) bool {
+		return message.IsSystem
+	}
+}
+
+// UserMessages keeps the messages written by participants
+func UserMessages() Filter {
+	return Not(SystemMessages())
+}
+
+// WithAttachment keeps the messages with an attachment of one of the kinds,
+// or with any attachment when no kind is given. Attachments are only there
+// when the chat was parsed with ParseAttachments.
+func WithAttachment(kinds ...AttachmentKind) Filter {
+	return func(message Message) bool {
+		if message.Attachment == nil {
+			return false
+		}
+		return len(kinds) == 0 || slices.Contains(kinds, message.Attachment.Kind)
+	}
+}
+
+// Matching keeps the messages whose text matches the regular expression
+func Matching(re *regexp.Regexp) Filter {
+	return func(message Message) bool {
+		return re.MatchString(message.Message)
+	}
+}
+
+// OnWeekdays keeps the messages sent on one of the days of the week
+func OnWeekdays(days ...time.Weekday) Filter {
+	return func(message Message) bool {
+		return slices.Contains(days, message.Date.Weekday())
+	}
+}
+
+// AtHours keeps the messages sent during one of the hours of the day, from
+// 0 to 23
+func AtHours(hours ...int) Filter {
+	return func(message Message) bool {
+		return slices.Contains(hours, message.Date.Hour())
+	}
+}
+
+// Apply returns the messages kept by the filter, in order
+func (f Filter) Apply(messages []Message) []Message {
+	var result []Message
+	for _, message := range messages {
+		if f(message) {
+			result = append(result, message)
+		}
+	}
+	return result
+}
+
+// Stream returns the messages of a stream, such as ParseReader's, kept by the
+// filter. Errors are passed through.
+func (f Filter) Stream(messages iter.Seq2[Message, error]) iter.Seq2[Message, error] {
+	return func(yield func(Message, error) bool) {
+		for message, err := range messages {
+			if err != nil || f(message) {
+				if !yield(message, err) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package parser
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestFilters tests selecting messages with filters
+func TestFilters(t *testing.T) {
+	content := "11/06/2018, 09:00 - Anna created group \"Trip\"\n" +
+		"11/06/2018, 09:05 - Anna: Where do we go?\n" +
+		"12/06/2018, 18:30 - Bob: IMG-20180612-WA0001.jpg (file attached)\n" +
+		"13/06/2018, 21:25 - Carl: the beach!\n" +
+		"16/06/2018, 10:00 - Bob: Beach it is"
+
+	messages, err := ParseString(content, &ParseStringOptions{ParseAttachments: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		description string
+		filter      Filter
+		expect      []int
+	}{
+		{"ByAuthors", ByAuthors("Bob", "Carl"), []int{2, 3, 4}},
+		{"Between", Between(time.Date(2018, 6, 12, 0, 0, 0, 0, time.UTC), time.Date(2018, 6, 16, 0, 0, 0, 0, time.UTC)), []int{2, 3}},
+		{"Between open end", Between(time.Date(2018, 6, 13, 0, 0, 0, 0, time.UTC), time.Time{}), []int{3, 4}},
+		{"SystemMessages", SystemMessages(), []int{0}},
+		{"UserMessages", UserMessages(), []int{1, 2, 3, 4}},
+		{"WithAttachment", WithAttachment(), []int{2}},
+		{"WithAttachment kind", WithAttachment(AttachmentVideo), nil},
+		{"Matching", Matching(regexp.MustCompile(`(?i)beach`)), []int{3, 4}},
+		{"OnWeekdays", OnWeekdays(time.Saturday, time.Sunday), []int{4}},
+		{"AtHours", AtHours(9, 10), []int{0, 1, 4}},
+		{"And", And(ByAuthors("Bob"), Matching(regexp.MustCompile(`Beach`))), []int{4}},
+		{"Or", Or(SystemMessages(), WithAttachment()), []int{0, 2}},
+		{"Not", Not(ByAuthors("Anna")), []int{0, 2, 3, 4}},
+		{"And without filters", And(), []int{0, 1, 2, 3, 4}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			var got []int
+			for _, message := range test.filter.Apply(messages) {
+				for i := range messages {
+					if messages[i].Date.Equal(message.Date) && messages[i].Message == message.Message {
+						got = append(got, i)
+					}
+				}
+			}
+
+			if len(got) != len(test.expect) {
+				t.Fatalf("Expected messages %v, got %v", test.expect, got)
+			}
+			for i := range got {
+				if got[i] != test.expect[i] {
+					t.Fatalf("Expected messages %v, got %v", test.expect, got)
+				}
+			}
+		})
+	}
+
+	t.Run("Stream", func(t *testing.T) {
+		filter := ByAuthors("Bob")
+
+		var count int
+		for message, err := range filter.Stream(ParseReader(strings.NewReader(content), nil)) {
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if *message.Author != "Bob" {
+				t.Errorf("Expected messages of Bob, got %v", *message.Author)
+			}
+			count++
+		}
+		if count != 2 {
+			t.Errorf("Expected 2 messages, got %d", count)
+		}
+
+		failing := func(yield func(Message, error) bool) {
+			yield(Message{}, errors.New("read failed"))
+		}
+		for _, err := range filter.Stream(failing) {
+			if err == nil {
+				t.Errorf("Expected the error to pass through")
+			}
+		}
+	})
+}
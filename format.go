@@ -0,0 +1,97 @@
+package parser
+
+import "strings"
+
+// layoutOf tells the iOS and Android header layouts apart from the part of a
+// header line before the author or message text
+func layoutOf(header string) Layout {
+	header = strings.TrimLeft(header, "\u200E\u200F")
+	switch {
+	case strings.HasPrefix(header, "["):
+		return LayoutBracketed
+	case strings.Contains(header, " - "):
+		return LayoutDash
+	}
+	return LayoutOther
+}
+
+// DetectFormat reports how the dates and headers of a chat are written,
+// including the day/month order ParseString would use without DaysFirst and
+// the check that decided it. The layout and year, clock and date separator
+// details are those of the first message.
+func DetectFormat(content string) *ChatFormat {
+	return detectFormat(content, ParseStringOptions{})
+}
+
+// detectFormat is DetectFormat for a chat parsed with the given options:
+// the DaysFirst and Locale options replace what would be detected
+func detectFormat(content string, options ParseStringOptions) *ChatFormat {
+	grammar, err := grammarFor(options.Locale)
+	if err != nil {
+		grammar, _ = grammarFor("")
+	}
+	format := &ChatFormat{}
+
+	var allDates [][]int
+	lines := newLineReader(strings.NewReader(content))
+	for {
+		line, err := lines.next()
+		if err != nil {
+			break
+		}
+
+		header, ok := grammar.matchLine(line.text)
+		if !ok {
+			continue
+		}
+		allDates = append(allDates, dateComponents(header))
+
+		if header.ampm != "" {
+			format.Clock12Hour = true
+		}
+		if format.Messages == 0 {
+			dateParts := strings.FieldsFunc(header.date, func(r rune) bool {
+				return r == '/' || r == '.' || r == '-' || r == ' '
+			})
+			format.YearFirst = len(dateParts[0]) > len(dateParts[2])
+			format.YearDigits = len(orderDateComponents(header.date)[2])
+			rest := header.date[len(dateParts[0]):]
+			format.DateSeparator = rest[:strings.IndexAny(rest, "0123456789")]
+			format.Seconds = len(regexSplitTime.Split(header.time, -1)) > 2
+			author := strings.LastIndex(line.text[:header.bodyStart], header.author)
+			if header.system || author < 0 {
+				author = header.bodyStart
+			}
+			format.Layout = layoutOf(line.text[:author])
+		}
+		format.Messages++
+	}
+
+	detected, heuristic, evidence := explainDateOrder(allDates)
+	format.DaysFirst = detected == nil || *detected
+	format.Heuristic = heuristic
+	format.Evidence = evidence
+	if options.DaysFirst != nil {
+		format.DaysFirst = *options.DaysFirst
+		format.Heuristic = HeuristicOption
+		format.Evidence = 0
+	}
+
+	if options.Locale != "" {
+		format.Locale = options.Locale
+	} else if locale := DetectLocale(content); locale != nil {
+		format.Locale = locale.Name
+	}
+
+	return format
+}
+
+// Options returns parse options fixing the detected day/month order and
+// locale, for parsing the chat once the format has been confirmed
+func (f *ChatFormat) Options() *ParseStringOptions {
+	daysFirst := f.DaysFirst
+	return &ParseStringOptions{
+		DaysFirst: &daysFirst,
+		Locale:    f.Locale,
+	}
+}
@@ -0,0 +1,64 @@
+package parser
+
+import (
+	"os"
+	"testing"
+)
+
+// TestDetectFormat tests the date format detection report
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		filePath string
+		expect   ChatFormat
+	}{
+		{
+			filePath: "test_data/default.txt",
+			expect: ChatFormat{DaysFirst: false, Heuristic: HeuristicDecreasing, Evidence: 1, YearDigits: 4,
+				DateSeparator: "/", Layout: LayoutDash, Locale: "en", Messages: 5},
+		},
+		{
+			filePath: "test_data/english_iphone-saved_contacts.txt",
+			expect: ChatFormat{DaysFirst: true, Heuristic: HeuristicAbove12, Evidence: 15, YearDigits: 4,
+				DateSeparator: "/", Seconds: true, Layout: LayoutBracketed, Locale: "en", Messages: 15},
+		},
+		{
+			filePath: "test_data/english_android-unsaved_contacts.txt",
+			expect: ChatFormat{DaysFirst: false, Heuristic: HeuristicAbove12, Evidence: 1, YearDigits: 2,
+				DateSeparator: "/", Layout: LayoutDash, Locale: "en", Messages: 20},
+		},
+	}
+
+	for _, test := range tests {
+		fileContents, err := os.ReadFile(test.filePath)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		format := DetectFormat(string(fileContents))
+		if *format != test.expect {
+			t.Errorf("DetectFormat(%s) = %+v, want %+v", test.filePath, *format, test.expect)
+		}
+	}
+
+	t.Run("12-hour year-first dates", func(t *testing.T) {
+		format := DetectFormat("[2018/06/13, 1:55:00 PM] a: m")
+		if !format.YearFirst || !format.Clock12Hour || format.Locale != "en" || format.DaysFirst {
+			t.Errorf("Unexpected format %+v", *format)
+		}
+	})
+
+	t.Run("Options", func(t *testing.T) {
+		format := DetectFormat("1/2/20, 10:00 - a: m\n1/3/20, 10:00 - a: m")
+		if format.DaysFirst || format.Heuristic != HeuristicChangeFrequency {
+			t.Fatalf("Unexpected format %+v", *format)
+		}
+
+		messages, err := ParseString("1/2/20, 10:00 - a: m", format.Options())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if messages[0].Date.Month() != 1 || messages[0].Date.Day() != 2 {
+			t.Errorf("Expected the detected order to be used, got %v", messages[0].Date)
+		}
+	})
+}
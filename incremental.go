@@ -0,0 +1,232 @@
+package parser
+
+import (
+	"io"
+	"maps"
+	"strings"
+	"unicode/utf8"
+)
+
+// ParserState is where ParseIncremental stopped reading a growing chat log.
+// It can be saved as JSON between calls.
+type ParserState struct {
+	// Offset and Line locate the last line read, which may grow when more is
+	// appended to the file, and Partial holds its text
+	Offset  int64  `json:"offset"`
+	Line    int    `json:"line"`
+	Partial string `json:"partial"`
+	// Incomplete holds the bytes of a character cut by the end of the text
+	// read so far, which JSON could not keep in Partial
+	Incomplete []byte `json:"incomplete,omitempty"`
+	// TrailingCR is set when the text read so far ends with "\r", which a
+	// "\n" starting the next tail completes into a single line break
+	TrailingCR bool `json:"trailingCR"`
+	// Pending is the last raw message read, including the partial line,
+	// which the next tail may continue
+	Pending *RawMessage `json:"pending,omitempty"`
+	// DaysFirst is the date order once decided. Guessed is set while no
+	// date has proved it.
+	DaysFirst *bool `json:"daysFirst,omitempty"`
+	Guessed   bool  `json:"guessed,omitempty"`
+	// DateOrder is the evidence of the date order from every date read so
+	// far, which a guessed order is checked against
+	DateOrder *dateOrderTracker `json:"dateOrder,omitempty"`
+	// Emitted is the number of messages returned so far
+	Emitted int `json:"emitted"`
+}
+
+type IncrementalResult struct {
+	// Revised is the last message returned by the previous call when the
+	// tail continues it, as with a multiline message cut by the end of the
+	// file, and nil otherwise
+	Revised     *Message     `json:"revised,omitempty"`
+	Messages    []Message    `json:"messages"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	// State is the state to resume from when more is appended
+	State *ParserState `json:"state"`
+}
+
+// ParseIncremental parses the tail appended to a chat log since the state was
+// returned, or the whole chat log when the state is nil, and returns the new
+// messages along with the state to resume from. The last message is always
+// returned, even though the next tail may continue it.
+//
+// Without the DaysFirst option the date order is detected from the first
+// call with messages and kept. If it was guessed from dates not above 12 and
+// ParseString would detect another order for the chat log read so far,
+// ErrDateOrderChanged is returned. Diagnostics
+// on the last line or message of the previous call may be reported again.
+func ParseIncremental(tail string, state *ParserState, options *ParseStringOptions) (*IncrementalResult, error) {
+	ctx, err := newParseContext(options)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := ParserState{}
+	if state != nil {
+		previous = *state
+	}
+	next := previous
+
+	text, offset := previous.Partial+string(previous.Incomplete)+tail, previous.Offset
+	if previous.TrailingCR && previous.Partial == "" && strings.HasPrefix(tail, "\n") {
+		text, offset = tail[1:], offset+1
+	}
+	next.Incomplete = nil
+	if n := incompleteSuffix(text); n > 0 {
+		next.Incomplete = []byte(text[len(text)-n:])
+		text = text[:len(text)-n]
+	}
+	reader := newLineReader(strings.NewReader(text))
+	reader.offset = offset
+	if previous.Line > 0 {
+		reader.number = previous.Line - 1
+	}
+
+	var lines []sourceLine
+	for {
+		line, err := reader.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	last := lines[len(lines)-1]
+	next.Offset, next.Line, next.Partial = last.offset, last.number, last.text
+	next.TrailingCR = strings.HasSuffix(text, "\r") || previous.TrailingCR && tail == ""
+
+	// The partial line is read again, so it is taken out of the pending
+	// message
+	assembler := messageAssembler{ctx: ctx}
+	if pending := previous.Pending; pending != nil && pending.Offset != previous.Offset {
+		current := *pending
+		current.Msg = strings.TrimSuffix(current.Msg, "\n"+previous.Partial)
+		assembler.current = &current
+	}
+
+	var rawMessages []RawMessage
+	for _, line := range lines {
+		if rawMsg, ok := assembler.push(line); ok {
+			rawMessages = append(rawMessages, rawMsg)
+		}
+	}
+	next.Pending = nil
+	if rawMsg, ok := assembler.flush(); ok {
+		rawMessages = append(rawMessages, rawMsg)
+		next.Pending = &rawMsg
+	}
+	if ctx.err != nil {
+		return nil, ctx.err
+	}
+
+	var matched []RawMessage
+	var headers []messageHeader
+	var dates [][]int
+	for _, rawMsg := range rawMessages {
+		header, ok := ctx.grammar.match(rawMsg)
+		if !ok {
+			continue
+		}
+		matched = append(matched, rawMsg)
+		headers = append(headers, header)
+		// The date of the last message of the previous call was counted
+		if revision := previous.Pending; revision == nil || rawMsg.Offset != revision.Offset {
+			dates = append(dates, dateComponents(header))
+		}
+	}
+
+	daysFirst, err := ctx.incrementalDaysFirst(&next, dates)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &IncrementalResult{State: &next}
+	for i, rawMsg := range matched {
+		// The first message may be the last one of the previous call
+		if revision := previous.Pending; revision != nil && rawMsg.Offset == revision.Offset {
+			// A final empty line is dropped from the message text
+			if strings.TrimSuffix(rawMsg.Msg, "\n") == strings.TrimSuffix(revision.Msg, "\n") {
+				continue
+			}
+			message := ctx.buildMessage(rawMsg, headers[i], daysFirst)
+			markEncryptionNotice(previous.Emitted-1, &message)
+			result.Revised = &message
+			continue
+		}
+
+		message := ctx.buildMessage(rawMsg, headers[i], daysFirst)
+		markEncryptionNotice(next.Emitted, &message)
+		next.Emitted++
+		result.Messages = append(result.Messages, message)
+	}
+	if ctx.err != nil {
+		return nil, ctx.err
+	}
+
+	result.Diagnostics = ctx.diagnostics
+	return result, nil
+}
+
+// incompleteSuffix returns the length of the UTF-8 sequence cut short at the
+// end of a text, or 0 if the text ends with a whole character
+func incompleteSuffix(text string) int {
+	for i := len(text) - 1; i >= 0 && i >= len(text)-utf8.UTFMax; i-- {
+		if !utf8.RuneStart(text[i]) {
+			continue
+		}
+		if utf8.FullRuneInString(text[i:]) {
+			return 0
+		}
+		return len(text) - i
+	}
+	return 0
+}
+
+// incrementalDaysFirst returns the date order of a tail with the given dates,
+// deciding it in the state on the first call with dates and checking a
+// guessed one against the dates of every call, as a Scanner does at the end
+func (ctx *parseContext) incrementalDaysFirst(state *ParserState, dates [][]int) (bool, error) {
+	if ctx.options.DaysFirst != nil {
+		return *ctx.options.DaysFirst, nil
+	}
+	if len(dates) == 0 {
+		return state.DaysFirst == nil || *state.DaysFirst, nil
+	}
+
+	// The evidence is copied, as the state it comes from may be resumed again
+	var tracker dateOrderTracker
+	if state.DateOrder != nil {
+		tracker = *state.DateOrder
+		tracker.LastByYear = maps.Clone(tracker.LastByYear)
+	}
+	for _, date := range dates {
+		tracker.add(date)
+	}
+	state.DateOrder = &tracker
+	proved := tracker.FirstAbove12 || tracker.SecondAbove12
+
+	if state.DaysFirst != nil {
+		if state.Guessed {
+			if resolveDaysFirst(ctx.options, tracker.result()) != *state.DaysFirst {
+				return false, ErrDateOrderChanged
+			}
+			state.Guessed = !proved
+		}
+		return *state.DaysFirst, nil
+	}
+
+	detected := tracker.result()
+	daysFirst := resolveDaysFirst(ctx.options, detected)
+	if !proved {
+		ctx.reportAmbiguousDateOrder(daysFirst, detected != nil)
+		if ctx.err != nil {
+			return false, ctx.err
+		}
+	}
+	state.DaysFirst = &daysFirst
+	state.Guessed = !proved
+	return daysFirst, nil
+}
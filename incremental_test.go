@@ -0,0 +1,175 @@
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// parseInParts parses a chat log split into parts with ParseIncremental,
+// saving the state as JSON between calls, and returns the messages with the
+// revisions applied
+func parseInParts(t *testing.T, parts []string, options *ParseStringOptions) ([]Message, error) {
+	t.Helper()
+
+	var messages []Message
+	var state *ParserState
+	for _, part := range parts {
+		result, err := ParseIncremental(part, state, options)
+		if err != nil {
+			return nil, err
+		}
+		if result.Revised != nil {
+			messages[len(messages)-1] = *result.Revised
+		}
+		messages = append(messages, result.Messages...)
+
+		data, err := json.Marshal(result.State)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		state = &ParserState{}
+		if err := json.Unmarshal(data, state); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	return messages, nil
+}
+
+// TestParseIncremental tests parsing a chat log as it grows
+func TestParseIncremental(t *testing.T) {
+	content := "13/06/2018, 21:25 - Anna: Hello\n" +
+		"there\n" +
+		"13/06/2018, 21:26 - Bob: <attached: photo.jpg>\n" +
+		"13/06/2018, 21:27 - Anna left\n" +
+		"14/06/2018, 08:00 - Anna: First line\n" +
+		"\n" +
+		"last line"
+
+	for _, newline := range []string{"\n", "\r\n", "\r"} {
+		content := strings.ReplaceAll(content, "\n", newline)
+		options := &ParseStringOptions{ParseAttachments: true}
+		expected, err := ParseString(content, options)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		t.Run("split anywhere "+strings.NewReplacer("\r", `\r`, "\n", `\n`).Replace(newline), func(t *testing.T) {
+			for i := 0; i <= len(content); i++ {
+				messages, err := parseInParts(t, []string{content[:i], content[i:]}, options)
+				if err != nil {
+					t.Fatalf("Split at %d: unexpected error: %v", i, err)
+				}
+				if !reflect.DeepEqual(messages, expected) {
+					t.Fatalf("Split at %d: expected %+v, got %+v", i, expected, messages)
+				}
+			}
+		})
+	}
+
+	t.Run("split anywhere in test data", func(t *testing.T) {
+		paths, err := filepath.Glob("test_data/*.txt")
+		if err != nil || len(paths) == 0 {
+			t.Fatalf("No test data: %v", err)
+		}
+		options := &ParseStringOptions{ParseAttachments: true}
+
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			content := string(data)
+			expected, err := ParseString(content, options)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			for i := 0; i <= len(content); i++ {
+				messages, err := parseInParts(t, []string{content[:i], content[i:]}, options)
+				// The order guessed from the first part may be contradicted
+				// by the second, but the messages must not be misdated
+				if errors.Is(err, ErrDateOrderChanged) {
+					continue
+				}
+				if err != nil {
+					t.Fatalf("%s split at %d: unexpected error: %v", path, i, err)
+				}
+				if !reflect.DeepEqual(messages, expected) {
+					t.Fatalf("%s split at %d: expected %+v, got %+v", path, i, expected, messages)
+				}
+			}
+		}
+	})
+
+	t.Run("line by line", func(t *testing.T) {
+		expected, _ := ParseString(content, nil)
+		parts := strings.SplitAfter(content, "\n")
+		messages, err := parseInParts(t, parts, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(messages, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, messages)
+		}
+	})
+
+	t.Run("revisions", func(t *testing.T) {
+		first, err := ParseIncremental("13/06/2018, 21:25 - Anna: Hello\n", nil, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(first.Messages) != 1 || first.State.Emitted != 1 || first.State.Line != 2 || first.State.Offset != 32 {
+			t.Fatalf("Unexpected result %+v", first)
+		}
+
+		second, err := ParseIncremental("there\n13/06/2018, 21:26 - Bob: Hi", first.State, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if second.Revised == nil || second.Revised.Message != "Hello\nthere" {
+			t.Errorf("Expected the first message continued, got %+v", second.Revised)
+		}
+		if len(second.Messages) != 1 || second.Messages[0].Message != "Hi" {
+			t.Errorf("Expected the message of Bob, got %+v", second.Messages)
+		}
+
+		third, err := ParseIncremental("\n13/06/2018, 21:27 - Anna: Bye", second.State, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if third.Revised != nil || len(third.Messages) != 1 || third.State.Line != 4 {
+			t.Errorf("Expected only a new message, got %+v", third)
+		}
+	})
+
+	t.Run("date order", func(t *testing.T) {
+		first, err := ParseIncremental("06/11/2018, 21:25 - Anna: Hello\n", nil, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(first.Diagnostics) != 1 || first.Diagnostics[0].Kind != DiagnosticUndetectedDateOrder {
+			t.Errorf("Expected the date order reported undetected, got %+v", first.Diagnostics)
+		}
+		if !first.State.Guessed || !*first.State.DaysFirst {
+			t.Errorf("Expected the days-first order guessed, got %+v", first.State)
+		}
+
+		_, err = ParseIncremental("06/13/2018, 21:25 - Anna: Bye", first.State, nil)
+		if !errors.Is(err, ErrDateOrderChanged) {
+			t.Errorf("Expected ErrDateOrderChanged, got %v", err)
+		}
+
+		second, err := ParseIncremental("13/11/2018, 21:25 - Anna: Bye", first.State, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if second.State.Guessed || second.Messages[0].Date.Month() != 11 {
+			t.Errorf("Expected the date order proved, got %+v", second.State)
+		}
+	})
+}
@@ -0,0 +1,178 @@
+package parser
+
+import "regexp"
+
+// builtinLocales are registered at init, English first so that it wins ties
+// between locales sharing a marker
+var builtinLocales = []*Locale{
+	{Name: "en", AM: []string{"AM", "a.m."}, PM: []string{"PM", "p.m."}, And: "and", SystemEvents: englishSystemEvents, OmittedMedia: englishOmittedMedia,
+		Deleted: []string{"This message was deleted"}, DeletedByOwner: []string{"You deleted this message"}, Edited: []string{"<This message was edited>"},
+		Owner: []string{"You", "you"}},
+	{Name: "de", AM: []string{"vorm."}, PM: []string{"nachm."}, And: "und", SystemEvents: germanSystemEvents, OmittedMedia: germanOmittedMedia,
+		Deleted: []string{"Diese Nachricht wurde gelöscht"}, DeletedByOwner: []string{"Du hast diese Nachricht gelöscht"}, Edited: []string{"<Diese Nachricht wurde bearbeitet>"},
+		Owner: []string{"Du", "du"}},
+	{Name: "es", AM: []string{"a. m."}, PM: []string{"p. m."}, And: "y", SystemEvents: spanishSystemEvents, OmittedMedia: spanishOmittedMedia,
+		Deleted: []string{"Se eliminó este mensaje", "Este mensaje fue eliminado"}, DeletedByOwner: []string{"Eliminaste este mensaje"}, Edited: []string{"<Se editó este mensaje.>"},
+		Owner: []string{"Tú", "tú"}},
+	{Name: "fr", And: "et", SystemEvents: frenchSystemEvents, OmittedMedia: frenchOmittedMedia,
+		Deleted: []string{"Ce message a été supprimé"}, DeletedByOwner: []string{"Vous avez supprimé ce message"}, Edited: []string{"<Ce message a été modifié>"},
+		Owner: []string{"Vous", "vous"}},
+	{Name: "pt", And: "e", SystemEvents: portugueseSystemEvents, OmittedMedia: portugueseOmittedMedia,
+		Deleted: []string{"Mensagem apagada", "Esta mensagem foi apagada"}, DeletedByOwner: []string{"Você apagou esta mensagem"}, Edited: []string{"<Mensagem editada>"},
+		Owner: []string{"Você", "você"}},
+	{Name: "nl", AM: []string{"a.m."}, PM: []string{"p.m."}, And: "en", OmittedMedia: dutchOmittedMedia,
+		Deleted: []string{"Dit bericht is verwijderd"}, DeletedByOwner: []string{"Je hebt dit bericht verwijderd"}, Edited: []string{"<Dit bericht is bewerkt>"},
+		Owner: []string{"Jij", "jij", "Je", "je"}},
+	{Name: "ko", AM: []string{"오전"}, PM: []string{"오후"}, MarkerFirst: true},
+	{Name: "ja", AM: []string{"午前"}, PM: []string{"午後"}, MarkerFirst: true},
+	{Name: "zh", AM: []string{"上午"}, PM: []string{"下午"}, MarkerFirst: true},
+	{Name: "ar", AM: []string{"ص"}, PM: []string{"م"}},
+	{Name: "fa", AM: []string{"ق.ظ."}, PM: []string{"ب.ظ."}},
+	{Name: "he", AM: []string{"לפנה״צ"}, PM: []string{"אחה״צ"}},
+	{Name: "tr", AM: []string{"ÖÖ"}, PM: []string{"ÖS"}},
+	{Name: "el", AM: []string{"π.μ."}, PM: []string{"μ.μ."}},
+	{Name: "hi", AM: []string{"पूर्वाह्न"}, PM: []string{"अपराह्न"}},
+	{Name: "th", AM: []string{"ก่อนเที่ยง"}, PM: []string{"หลังเที่ยง"}},
+	{Name: "vi", AM: []string{"SA"}, PM: []string{"CH"}},
+	{Name: "ms", AM: []string{"PG"}, PM: []string{"PTG"}},
+	{Name: "sv", AM: []string{"fm"}, PM: []string{"em"}},
+	{Name: "fi", AM: []string{"ap."}, PM: []string{"ip."}},
+}
+
+var englishSystemEvents = []SystemEventPattern{
+	{Type: SystemEventEncryption, Pattern: regexp.MustCompile(`end-to-end encrypt`)},
+	{Type: SystemEventGroupCreated, Pattern: regexp.MustCompile(`^(?P<actor>.+?) created group ["“](?P<value>.*)["”]$`)},
+	{Type: SystemEventGroupCreated, Pattern: regexp.MustCompile(`^(?P<actor>.+?) created this group$`)},
+	{Type: SystemEventMemberJoined, Pattern: regexp.MustCompile(`^(?P<actor>.+?) joined using this group['’]s invite link$`)},
+	{Type: SystemEventMemberAdded, Pattern: regexp.MustCompile(`^(?P<actor>.+?) added (?P<targets>.+)$`)},
+	{Type: SystemEventMemberRemoved, Pattern: regexp.MustCompile(`^(?P<actor>.+?) removed (?P<targets>.+)$`)},
+	{Type: SystemEventMemberLeft, Pattern: regexp.MustCompile(`^(?P<actor>.+?) left$`)},
+	{Type: SystemEventSubjectChanged, Pattern: regexp.MustCompile(`^(?P<actor>.+?) changed the (?:subject|group name) from ["“](?P<previous>.*)["”] to ["“](?P<value>.*)["”]$`)},
+	{Type: SystemEventSubjectChanged, Pattern: regexp.MustCompile(`^(?P<actor>.+?) changed the (?:subject|group name) to ["“](?P<value>.*)["”]$`)},
+	{Type: SystemEventIconChanged, Pattern: regexp.MustCompile(`^(?P<actor>.+?) changed (?:this group['’]s|the group) icon$`), Value: "changed"},
+	{Type: SystemEventIconChanged, Pattern: regexp.MustCompile(`^(?P<actor>.+?) deleted (?:this group['’]s|the group) icon$`), Value: "deleted"},
+	{Type: SystemEventDescriptionChanged, Pattern: regexp.MustCompile(`^(?P<actor>.+?) changed the group description$`), Value: "changed"},
+	{Type: SystemEventDescriptionChanged, Pattern: regexp.MustCompile(`^(?P<actor>.+?) deleted the group description$`), Value: "deleted"},
+	{Type: SystemEventNumberChanged, Pattern: regexp.MustCompile(`^(?P<actor>.+?) changed their phone number to a new number\.`)},
+	{Type: SystemEventNumberChanged, Pattern: regexp.MustCompile(`^(?P<actor>.+?) changed to (?P<value>\+[\d\s()-]+)$`)},
+	{Type: SystemEventSecurityCodeChanged, Pattern: regexp.MustCompile(`^Your security code with (?P<targets>.+?) changed\.`)},
+	{Type: SystemEventDisappearingToggled, Pattern: regexp.MustCompile(`^(?P<actor>.+?) turned on disappearing messages\.`), Value: "on"},
+	{Type: SystemEventDisappearingToggled, Pattern: regexp.MustCompile(`^(?P<actor>.+?) turned off disappearing messages\.`), Value: "off"},
+	{Type: SystemEventAdminPromoted, Pattern: regexp.MustCompile(`^(?P<targets>You)['’]re now an admin$`)},
+	{Type: SystemEventAdminPromoted, Pattern: regexp.MustCompile(`^(?P<actor>.+?) made (?P<targets>.+?) (?:an|a group) admin$`)},
+}
+
+var germanSystemEvents = []SystemEventPattern{
+	{Type: SystemEventEncryption, Pattern: regexp.MustCompile(`Ende-zu-Ende-verschlüsselt`)},
+	{Type: SystemEventGroupCreated, Pattern: regexp.MustCompile(`^(?P<actor>.+?) ha(?:t|st) die Gruppe ["„“](?P<value>.*)["“”] erstellt$`)},
+	{Type: SystemEventSubjectChanged, Pattern: regexp.MustCompile(`^(?P<actor>.+?) ha(?:t|st) den Betreff von ["„“](?P<previous>.*)["“”] zu ["„“](?P<value>.*)["“”] geändert$`)},
+	{Type: SystemEventMemberLeft, Pattern: regexp.MustCompile(`^(?P<actor>.+?) ha(?:t|st) die Gruppe verlassen$`)},
+	{Type: SystemEventMemberJoined, Pattern: regexp.MustCompile(`^(?P<actor>.+?) (?:ist|bist) über den Einladungslink dieser Gruppe beigetreten$`)},
+	{Type: SystemEventIconChanged, Pattern: regexp.MustCompile(`^(?P<actor>.+?) ha(?:t|st) das Gruppenbild geändert$`), Value: "changed"},
+	{Type: SystemEventDescriptionChanged, Pattern: regexp.MustCompile(`^(?P<actor>.+?) ha(?:t|st) die Gruppenbeschreibung geändert$`), Value: "changed"},
+	{Type: SystemEventSecurityCodeChanged, Pattern: regexp.MustCompile(`^Deine Sicherheitsnummer für (?P<targets>.+?) hat sich geändert\.`)},
+	{Type: SystemEventMemberAdded, Pattern: regexp.MustCompile(`^(?P<actor>.+?) ha(?:t|st) (?P<targets>.+) hinzugefügt$`)},
+	{Type: SystemEventMemberRemoved, Pattern: regexp.MustCompile(`^(?P<actor>.+?) ha(?:t|st) (?P<targets>.+) entfernt$`)},
+}
+
+var spanishSystemEvents = []SystemEventPattern{
+	{Type: SystemEventEncryption, Pattern: regexp.MustCompile(`cifrad[oa]s de extremo a extremo`)},
+	{Type: SystemEventGroupCreated, Pattern: regexp.MustCompile(`^(?P<actor>.+?) creó el grupo ["“](?P<value>.*)["”]$`)},
+	{Type: SystemEventMemberAdded, Pattern: regexp.MustCompile(`^(?P<actor>.+?) añadió a (?P<targets>.+)$`)},
+	{Type: SystemEventMemberRemoved, Pattern: regexp.MustCompile(`^(?P<actor>.+?) eliminó a (?P<targets>.+)$`)},
+	{Type: SystemEventMemberLeft, Pattern: regexp.MustCompile(`^(?P<actor>.+?) salió del grupo$`)},
+	{Type: SystemEventMemberJoined, Pattern: regexp.MustCompile(`^(?P<actor>.+?) se unió usando el enlace de invitación de este grupo$`)},
+	{Type: SystemEventSubjectChanged, Pattern: regexp.MustCompile(`^(?P<actor>.+?) cambió el asunto de ["“](?P<previous>.*)["”] a ["“](?P<value>.*)["”]$`)},
+	{Type: SystemEventIconChanged, Pattern: regexp.MustCompile(`^(?P<actor>.+?) cambió el ícono de este grupo$`), Value: "changed"},
+	{Type: SystemEventDescriptionChanged, Pattern: regexp.MustCompile(`^(?P<actor>.+?) cambió la descripción del grupo$`), Value: "changed"},
+}
+
+var frenchSystemEvents = []SystemEventPattern{
+	{Type: SystemEventEncryption, Pattern: regexp.MustCompile(`chiffrés de bout en bout`)},
+	{Type: SystemEventGroupCreated, Pattern: regexp.MustCompile(`^(?P<actor>.+?) a créé le groupe [«"“]\s?(?P<value>.*?)\s?[»"”]$`)},
+	{Type: SystemEventMemberAdded, Pattern: regexp.MustCompile(`^(?P<actor>.+?) a ajouté (?P<targets>.+)$`)},
+	{Type: SystemEventMemberRemoved, Pattern: regexp.MustCompile(`^(?P<actor>.+?) a retiré (?P<targets>.+)$`)},
+	{Type: SystemEventMemberLeft, Pattern: regexp.MustCompile(`^(?P<actor>.+?) est parti(?:e)?$`)},
+	{Type: SystemEventMemberJoined, Pattern: regexp.MustCompile(`^(?P<actor>.+?) a rejoint ce groupe via le lien d['’]invitation$`)},
+	{Type: SystemEventSubjectChanged, Pattern: regexp.MustCompile(`^(?P<actor>.+?) a modifié le sujet de [«"“]\s?(?P<previous>.*?)\s?[»"”] en [«"“]\s?(?P<value>.*?)\s?[»"”]$`)},
+	{Type: SystemEventIconChanged, Pattern: regexp.MustCompile(`^(?P<actor>.+?) a modifié l['’]icône de ce groupe$`), Value: "changed"},
+	{Type: SystemEventDescriptionChanged, Pattern: regexp.MustCompile(`^(?P<actor>.+?) a modifié la description du groupe$`), Value: "changed"},
+}
+
+var portugueseSystemEvents = []SystemEventPattern{
+	{Type: SystemEventEncryption, Pattern: regexp.MustCompile(`criptografia de ponta a ponta`)},
+	{Type: SystemEventGroupCreated, Pattern: regexp.MustCompile(`^(?P<actor>.+?) criou o grupo ["“](?P<value>.*)["”]$`)},
+	{Type: SystemEventMemberAdded, Pattern: regexp.MustCompile(`^(?P<actor>.+?) adicionou (?P<targets>.+)$`)},
+	{Type: SystemEventMemberRemoved, Pattern: regexp.MustCompile(`^(?P<actor>.+?) removeu (?P<targets>.+)$`)},
+	{Type: SystemEventMemberLeft, Pattern: regexp.MustCompile(`^(?P<actor>.+?) saiu$`)},
+	{Type: SystemEventMemberJoined, Pattern: regexp.MustCompile(`^(?P<actor>.+?) entrou usando o link de convite deste grupo$`)},
+	{Type: SystemEventSubjectChanged, Pattern: regexp.MustCompile(`^(?P<actor>.+?) mudou o assunto de ["“](?P<previous>.*)["”] para ["“](?P<value>.*)["”]$`)},
+	{Type: SystemEventIconChanged, Pattern: regexp.MustCompile(`^(?P<actor>.+?) mudou a imagem deste grupo$`), Value: "changed"},
+	{Type: SystemEventDescriptionChanged, Pattern: regexp.MustCompile(`^(?P<actor>.+?) mudou a descrição do grupo$`), Value: "changed"},
+}
+
+var englishOmittedMedia = map[string]AttachmentKind{
+	"<media omitted>":      AttachmentUnknown,
+	"image omitted":        AttachmentImage,
+	"video omitted":        AttachmentVideo,
+	"audio omitted":        AttachmentAudio,
+	"sticker omitted":      AttachmentSticker,
+	"gif omitted":          AttachmentGIF,
+	"document omitted":     AttachmentDocument,
+	"contact card omitted": AttachmentContactCard,
+}
+
+var germanOmittedMedia = map[string]AttachmentKind{
+	"<medien ausgeschlossen>":  AttachmentUnknown,
+	"bild weggelassen":         AttachmentImage,
+	"video weggelassen":        AttachmentVideo,
+	"audio weggelassen":        AttachmentAudio,
+	"sticker weggelassen":      AttachmentSticker,
+	"gif weggelassen":          AttachmentGIF,
+	"dokument weggelassen":     AttachmentDocument,
+	"kontaktkarte ausgelassen": AttachmentContactCard,
+}
+
+var spanishOmittedMedia = map[string]AttachmentKind{
+	"<multimedia omitido>":        AttachmentUnknown,
+	"imagen omitida":              AttachmentImage,
+	"video omitido":               AttachmentVideo,
+	"audio omitido":               AttachmentAudio,
+	"sticker omitido":             AttachmentSticker,
+	"gif omitido":                 AttachmentGIF,
+	"documento omitido":           AttachmentDocument,
+	"tarjeta de contacto omitida": AttachmentContactCard,
+}
+
+var frenchOmittedMedia = map[string]AttachmentKind{
+	"<médias omis>":       AttachmentUnknown,
+	"image absente":       AttachmentImage,
+	"vidéo absente":       AttachmentVideo,
+	"audio omis":          AttachmentAudio,
+	"sticker omis":        AttachmentSticker,
+	"gif retiré":          AttachmentGIF,
+	"document omis":       AttachmentDocument,
+	"fiche contact omise": AttachmentContactCard,
+}
+
+var portugueseOmittedMedia = map[string]AttachmentKind{
+	"<mídia oculta>":            AttachmentUnknown,
+	"imagem ocultada":           AttachmentImage,
+	"vídeo omitido":             AttachmentVideo,
+	"áudio ocultado":            AttachmentAudio,
+	"figurinha omitida":         AttachmentSticker,
+	"gif omitido":               AttachmentGIF,
+	"documento omitido":         AttachmentDocument,
+	"cartão do contato omitido": AttachmentContactCard,
+}
+
+var dutchOmittedMedia = map[string]AttachmentKind{
+	"<media weggelaten>":      AttachmentUnknown,
+	"afbeelding weggelaten":   AttachmentImage,
+	"video weggelaten":        AttachmentVideo,
+	"audio weggelaten":        AttachmentAudio,
+	"sticker weggelaten":      AttachmentSticker,
+	"gif weggelaten":          AttachmentGIF,
+	"document weggelaten":     AttachmentDocument,
+	"contactkaart weggelaten": AttachmentContactCard,
+}
@@ -0,0 +1,339 @@
+package parser
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// headerLexer reads the header at the start of a line or raw message in a
+// single pass. It accepts the same headers as the regexes it replaced, kept
+// in lexer_test.go, trying the choices in the order they backtracked.
+type headerLexer struct {
+	s       string
+	markers []string
+	// truncated is set when a choice failed at the end of the text, so that
+	// more text after it could change the header
+	truncated bool
+}
+
+// lexResult holds the header read as a system message, and as a message
+// with an author when it has one
+type lexResult struct {
+	system, user     messageHeader
+	isSystem, isUser bool
+	truncated        bool
+}
+
+// lex reads the header at the start of s
+func (g *headerGrammar) lex(s string) lexResult {
+	l := &headerLexer{s: s, markers: g.alternatives}
+	var result lexResult
+
+	i := 0
+	for l.prefix(i, "\u200E") || l.prefix(i, "\u200F") {
+		i += len("\u200E")
+	}
+	if l.byteIs(i, '[') {
+		i++
+	}
+
+	// Date, as three numbers of up to four digits
+	dateStart := i
+	for k := 0; k < 3; k++ {
+		if k > 0 {
+			if !l.byteIn(i, "-/.") {
+				return l.result(result)
+			}
+			i++
+			if l.space(i) {
+				i++
+			}
+		}
+		n := l.digits(i)
+		if n < 1 || n > 4 {
+			return l.result(result)
+		}
+		i += n
+	}
+	dateEnd := i
+
+	if l.byteIn(i, ",.") {
+		i++
+	} else if l.prefix(i, "،") {
+		i += len("،")
+	}
+	if !l.space(i) {
+		return l.result(result)
+	}
+	i++
+
+	// Marker written before the time, and anything up to the time
+	preStart, preEnd := i, i
+	for _, marker := range l.markers {
+		if n, ok := l.marker(marker, i); ok {
+			preEnd = i + n
+			break
+		}
+	}
+	for i = preEnd; ; i++ {
+		c, ok := l.at(i)
+		if !ok {
+			return l.result(result)
+		}
+		if isDigit(c) {
+			break
+		}
+	}
+
+	// Time, with optional seconds
+	timeStart := i
+	for k := 0; k < 3; k++ {
+		if k > 0 {
+			// Seconds are optional
+			if !l.byteIn(i, ".:") || k == 2 && !l.digitAt(i+1) {
+				if k == 2 {
+					break
+				}
+				return l.result(result)
+			}
+			i++
+		}
+		n := l.digits(i)
+		if n < 1 || n > 2 {
+			return l.result(result)
+		}
+		i += n
+	}
+	timeEnd := i
+
+	header := messageHeader{
+		date: s[dateStart:dateEnd],
+		time: s[timeStart:timeEnd],
+	}
+	pre := s[preStart:preEnd]
+
+	if ampm, end, ok := l.tail(timeEnd, func(int) bool { return true }); ok {
+		result.system = header
+		result.system.system = true
+		result.system.ampm = pre + ampm
+		result.system.bodyStart = end
+		result.isSystem = true
+	}
+
+	colon := 0
+	if ampm, end, ok := l.tail(timeEnd, func(end int) bool {
+		var found bool
+		colon, found = l.author(end)
+		return found
+	}); ok {
+		result.user = header
+		result.user.ampm = pre + ampm
+		result.user.author = s[end:colon]
+		result.user.bodyStart = colon + 2
+		result.isUser = true
+	}
+
+	return l.result(result)
+}
+
+// result returns the result with the truncated flag of the lexer
+func (l *headerLexer) result(result lexResult) lexResult {
+	result.truncated = l.truncated
+	return result
+}
+
+// tail reads what follows the time: a marker, a closing bracket, a dash or a
+// colon, then a space. It returns the marker and the end of the first
+// choice for which accept succeeds.
+func (l *headerLexer) tail(i int, accept func(end int) bool) (string, int, bool) {
+	var ampms [8][3]int // marker start, marker end and next position
+	count := 0
+	if w := l.wideSpace(i); w > 0 {
+		for _, marker := range l.markers {
+			if n, ok := l.marker(marker, i+w); ok && count < len(ampms)-1 {
+				ampms[count] = [3]int{i + w, i + w + n, i + w + n}
+				count++
+			}
+		}
+	}
+	ampms[count] = [3]int{i, i, i}
+	count++
+
+	for _, ampm := range ampms[:count] {
+		j := ampm[2]
+		brackets := [2]int{j, j}
+		nb := 0
+		if l.byteIs(j, ']') {
+			brackets[nb] = j + 1
+			nb++
+		}
+		brackets[nb] = j
+		nb++
+
+		for _, k := range brackets[:nb] {
+			var separators [3]int
+			ns := 0
+			if l.space(k) && l.byteIs(k+1, '-') {
+				separators[ns] = k + 2
+				ns++
+			}
+			if l.byteIs(k, ':') {
+				separators[ns] = k + 1
+				ns++
+			}
+			separators[ns] = k
+			ns++
+
+			for _, m := range separators[:ns] {
+				if l.space(m) && accept(m+1) {
+					return l.s[ampm[0]:ampm[1]], m + 1, true
+				}
+			}
+		}
+	}
+	return "", 0, false
+}
+
+// author returns the position of the colon ending the author starting at i:
+// the first colon followed by a space after at least one character, on the
+// same line
+func (l *headerLexer) author(i int) (int, bool) {
+	c, ok := l.at(i)
+	if !ok || c == '\n' {
+		return 0, false
+	}
+	_, size := utf8.DecodeRuneInString(l.s[i:])
+	for j := i + size; ; j++ {
+		c, ok := l.at(j)
+		if !ok || c == '\n' {
+			return 0, false
+		}
+		if c == ':' && l.space(j+1) {
+			return j, true
+		}
+	}
+}
+
+// marker returns the length of the marker with the canonical form key at i,
+// written with or without dots and spaces between and after its characters
+func (l *headerLexer) marker(key string, i int) (int, bool) {
+	j := i
+	for k, want := range key {
+		if k > 0 {
+			if l.byteIs(j, '.') {
+				j++
+			}
+			j += l.wideSpace(j)
+		}
+		if j >= len(l.s) {
+			l.truncated = true
+			return 0, false
+		}
+		r, size := utf8.DecodeRuneInString(l.s[j:])
+		if !foldEqual(r, want) {
+			return 0, false
+		}
+		j += size
+	}
+	if l.byteIs(j, '.') {
+		j++
+	}
+	return j - i, true
+}
+
+// at returns the byte at i, noting when the text ends there
+func (l *headerLexer) at(i int) (byte, bool) {
+	if i >= len(l.s) {
+		l.truncated = true
+		return 0, false
+	}
+	return l.s[i], true
+}
+
+func (l *headerLexer) byteIs(i int, c byte) bool {
+	b, ok := l.at(i)
+	return ok && b == c
+}
+
+func (l *headerLexer) byteIn(i int, set string) bool {
+	b, ok := l.at(i)
+	return ok && strings.IndexByte(set, b) >= 0
+}
+
+func (l *headerLexer) prefix(i int, token string) bool {
+	if i >= len(l.s) {
+		l.truncated = true
+		return false
+	}
+	return strings.HasPrefix(l.s[i:], token)
+}
+
+// space reports whether an ASCII space, as matched by \s, is at i
+func (l *headerLexer) space(i int) bool {
+	b, ok := l.at(i)
+	return ok && isSpace(b)
+}
+
+// wideSpace returns the length of an ASCII, non-breaking or narrow
+// non-breaking space at i, or 0
+func (l *headerLexer) wideSpace(i int) int {
+	switch {
+	case l.space(i):
+		return 1
+	case l.prefix(i, "\u00A0"):
+		return len("\u00A0")
+	case l.prefix(i, "\u202F"):
+		return len("\u202F")
+	}
+	return 0
+}
+
+func (l *headerLexer) digitAt(i int) bool {
+	b, ok := l.at(i)
+	return ok && isDigit(b)
+}
+
+// digits returns the length of the run of ASCII digits at i
+func (l *headerLexer) digits(i int) int {
+	n := 0
+	for l.digitAt(i + n) {
+		n++
+	}
+	return n
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isSpace(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '\f', '\r':
+		return true
+	}
+	return false
+}
+
+// foldEqual reports whether r equals want under simple case folding
+func foldEqual(r, want rune) bool {
+	if r == want {
+		return true
+	}
+	if r < utf8.RuneSelf && want < utf8.RuneSelf {
+		return asciiLower(r) == asciiLower(want)
+	}
+	for f := unicode.SimpleFold(want); f != want; f = unicode.SimpleFold(f) {
+		if f == r {
+			return true
+		}
+	}
+	return false
+}
+
+func asciiLower(r rune) rune {
+	if 'A' <= r && r <= 'Z' {
+		return r + 'a' - 'A'
+	}
+	return r
+}
@@ -0,0 +1,223 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// The regexes the header lexer replaced, used as its oracle. sharedRegex is
+// completed with the AM/PM markers of the locales in use, which may come
+// before or after the time.
+const (
+	sharedRegex           = `^(?:[\x{200E}\x{200F}])*\[?(?P<date>\d{1,4}[-/.]\s?\d{1,4}[-/.]\s?\d{1,4})[,.،]?\s(?:(?P<pre>%[1]s)[\s\x{00A0}\x{202F}]?)?\D*?(?P<time>\d{1,2}[.:]\d{1,2}(?:[.:]\d{1,2})?)(?:[\s\x{00A0}\x{202F}](?P<ampm>%[1]s))?\]?(?:\s-|:)?\s`
+	authorAndMessageRegex = `(?P<author>.+?):\s(?P<message>(?s:.*))`
+	messageRegex          = `(?P<message>(?s:.*))`
+	// noMarkers is used in place of the marker alternation for locales
+	// without AM/PM markers, and never matches
+	noMarkers = `[^\x00-\x{10FFFF}]`
+)
+
+// headerOracle matches headers with the regexes of a grammar
+type headerOracle struct {
+	parser *regexp.Regexp
+	system *regexp.Regexp
+}
+
+func newHeaderOracle(grammar *headerGrammar) *headerOracle {
+	var alternatives []string
+	for _, key := range grammar.alternatives {
+		var parts []string
+		for _, r := range key {
+			parts = append(parts, regexp.QuoteMeta(string(r)))
+		}
+		alternatives = append(alternatives, strings.Join(parts, `\.?[\s\x{00A0}\x{202F}]?`)+`\.?`)
+	}
+	pattern := strings.Join(alternatives, "|")
+	if pattern == "" {
+		pattern = noMarkers
+	}
+
+	shared := fmt.Sprintf(sharedRegex, pattern)
+	return &headerOracle{
+		parser: regexp.MustCompile(`(?i)` + shared + authorAndMessageRegex),
+		system: regexp.MustCompile(`(?i)` + shared + messageRegex),
+	}
+}
+
+// match matches s as a system message or as a message with an author
+func (o *headerOracle) match(s string, system bool) (messageHeader, bool) {
+	re := o.parser
+	if system {
+		re = o.system
+	}
+
+	loc := re.FindStringSubmatchIndex(s)
+	if loc == nil {
+		return messageHeader{}, false
+	}
+	group := func(name string) string {
+		i := re.SubexpIndex(name)
+		if i < 0 || loc[2*i] < 0 {
+			return ""
+		}
+		return s[loc[2*i]:loc[2*i+1]]
+	}
+
+	return messageHeader{
+		system:    system,
+		date:      group("date"),
+		time:      group("time"),
+		ampm:      group("pre") + group("ampm"),
+		author:    group("author"),
+		bodyStart: loc[2*re.SubexpIndex("message")],
+	}, true
+}
+
+// checkLexer compares the lexer with the oracle on s
+func checkLexer(t *testing.T, grammar *headerGrammar, oracle *headerOracle, s string) {
+	t.Helper()
+
+	result := grammar.lex(s)
+	for _, system := range []bool{true, false} {
+		expected, expectedOK := oracle.match(s, system)
+		got, ok := result.user, result.isUser
+		if system {
+			got, ok = result.system, result.isSystem
+		}
+		if ok != expectedOK || ok && got != expected {
+			t.Errorf("%q as system %v: expected %+v (%v), got %+v (%v)", s, system, expected, expectedOK, got, ok)
+		}
+	}
+}
+
+// lexerSamples are headers and near headers of every supported shape
+var lexerSamples = []string{
+	"13/06/2018, 21:25 - Anna: Hello",
+	"13/06/2018, 21:25 - Anna left",
+	"13/06/2018, 21:25 - Anna: ",
+	"13/06/2018, 21:25 - Anna:",
+	"13/06/2018, 21:25 - Anna:\tHello: you",
+	"13/06/2018, 21:25 -",
+	"13/06/2018, 21:25 - ",
+	"13/06/2018, 21:25 ",
+	"13/06/2018, 21:25",
+	"13/06/2018 21:25: Anna: Hello",
+	"13.06.18, 21.25 - Anna: Hello",
+	"2018-06-13, 21:25:59 - Anna: Hello",
+	"2018-06-13 21:25:590 - Anna: Hello",
+	"13/06/20180, 21:25 - Anna: Hello",
+	"13/06/2018, 211:25 - Anna: Hello",
+	"13/ 06/ 2018, 21:25 - Anna: Hello",
+	"13/06/2018، 21:25 - Anna: Hello",
+	"[13/06/2018, 21:25:30] Anna: Hello",
+	"[13/06/2018, 21:25:30]",
+	"[13/06/2018, 21:25:30] ",
+	"\u200E[13/06/2018, 21:25:30] Anna: \u200Eimage omitted",
+	"\u200F\u200E[13/06/2018, 21:25:30] Anna: Hello",
+	"[6/13/18, 9:25:30 PM] Anna: Hello",
+	"[6/13/18, 9:25:30\u202FPM] Anna: Hello",
+	"[6/13/18, 9:25:30\u00A0p.m.] Anna: Hello",
+	"6/13/18, 9:25 p. m. - Anna: Hello",
+	"6/13/18, 9:25 pm - Anna: Hello",
+	"6/13/18, 9:25 P",
+	"6/13/18, 9:25 PM",
+	"6/13/18, 9:25 PMX - Anna: Hello",
+	"13.06.18, 9:25 nachm. - Anna: Hello",
+	"2018. 6. 13. 오후 9:25 - Anna: Hello",
+	"2018. 6. 13. 오후 9:25, Anna : Hello",
+	"2018/6/13 下午9:25 - Anna: Hello",
+	"13/06/2018, ÖS 9:25 - Anna: Hello",
+	"13/06/2018, öö 9:25 - Anna: Hello",
+	"13/06/2018, at 21:25 - Anna: Hello",
+	"13/06/2018, - Anna: Hello",
+	"Hello 13/06/2018, 21:25 - Anna: Hello",
+	"13/06/2018, 21:25 - : Hello",
+	"13/06/2018, 21:25 - Anna\nBob: Hello",
+	"13/06/2018, 21:25\n- Anna: Hello",
+	"6/13/18, 9:25 p\nm - Anna: Hello",
+	"",
+}
+
+// TestHeaderLexer tests that the header lexer reads the headers the regexes
+// it replaced matched
+func TestHeaderLexer(t *testing.T) {
+	grammars := map[string]*headerGrammar{}
+	for _, name := range []string{"", "en", "ko", "sv"} {
+		grammar, err := grammarFor(name)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		grammars[name] = grammar
+	}
+	grammars["none"] = newHeaderGrammar([]*Locale{{Name: "none"}})
+
+	t.Run("samples", func(t *testing.T) {
+		for name, grammar := range grammars {
+			oracle := newHeaderOracle(grammar)
+			for _, sample := range lexerSamples {
+				for _, s := range []string{sample, sample + "\nmore: text", sample + "\r\n"} {
+					checkLexer(t, grammar, oracle, s)
+				}
+			}
+			if t.Failed() {
+				t.Fatalf("Grammar %q failed", name)
+			}
+		}
+	})
+
+	t.Run("test data", func(t *testing.T) {
+		paths, err := filepath.Glob("test_data/*.txt")
+		if err != nil || len(paths) == 0 {
+			t.Fatalf("No test data: %v", err)
+		}
+
+		grammar := grammars[""]
+		oracle := newHeaderOracle(grammar)
+		for _, path := range paths {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			lines := strings.Split(string(content), "\n")
+			for _, line := range lines {
+				checkLexer(t, grammar, oracle, line)
+			}
+			for _, rawMsg := range makeArrayOfMessages(toSourceLines(lines), &parseContext{grammar: grammar}) {
+				checkLexer(t, grammar, oracle, rawMsg.Msg)
+			}
+		}
+	})
+
+	t.Run("cached headers", func(t *testing.T) {
+		grammar := grammars[""]
+		oracle := newHeaderOracle(grammar)
+		for _, sample := range lexerSamples {
+			for _, next := range []string{"", "more: text", "- Anna: Hello", ": Hello", "m - Anna: Hello"} {
+				lines := []string{sample, next}
+				rawMessages := makeArrayOfMessages(toSourceLines(lines), &parseContext{grammar: grammar})
+				if len(rawMessages) == 0 {
+					continue
+				}
+				rawMsg := rawMessages[0]
+				expected, expectedOK := oracle.match(rawMsg.Msg, rawMsg.System)
+				got, ok := grammar.match(rawMsg)
+				if ok != expectedOK || got != expected {
+					t.Errorf("%q: expected %+v (%v), got %+v (%v)", rawMsg.Msg, expected, expectedOK, got, ok)
+				}
+			}
+		}
+	})
+}
+
+// toSourceLines numbers lines as the line reader does
+func toSourceLines(lines []string) []sourceLine {
+	result := make([]sourceLine, len(lines))
+	for i, line := range lines {
+		result[i] = sourceLine{text: line, number: i + 1}
+	}
+	return result
+}
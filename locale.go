@@ -0,0 +1,230 @@
+package parser
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ErrUnknownLocale is returned when ParseStringOptions.Locale names a locale
+// that is not registered
+var ErrUnknownLocale = errors.New("parser: unknown locale")
+
+// Locale describes how WhatsApp writes chats for one phone language
+type Locale struct {
+	Name string // language tag, e.g. "de"
+	// AM and PM list the markers of 12-hour times, most common first
+	AM []string
+	PM []string
+	// MarkerFirst is set when the marker is written before the time, as in
+	// "오후 3:04"
+	MarkerFirst bool
+	// And is the word joining the last two names of a list in system
+	// messages, as in "Anna, Bob and Carl"
+	And          string
+	SystemEvents []SystemEventPattern
+	// OmittedMedia maps the placeholders written in place of media in
+	// exports made without media, in lower case, to the kind of the media
+	OmittedMedia map[string]AttachmentKind
+	// Deleted and DeletedByOwner list the texts written in place of deleted
+	// messages, and Edited the markers ending edited messages. Format
+	// writes the first of each.
+	Deleted        []string
+	DeletedByOwner []string
+	Edited         []string
+	// Owner lists the words naming the owner of the exporting phone in
+	// system messages, as "You" in "You added Anna"
+	Owner []string
+
+	listSeparator *regexp.Regexp
+}
+
+var (
+	localesMu sync.RWMutex
+	locales   []*Locale
+	// grammars caches the header grammar of each forced locale, and of all
+	// locales under the empty name
+	grammars = make(map[string]*headerGrammar)
+)
+
+func init() {
+	for _, locale := range builtinLocales {
+		RegisterLocale(locale)
+	}
+}
+
+// RegisterLocale adds a locale, or replaces the registered locale with the
+// same name. Parsing without a forced locale accepts the markers and system
+// messages of every registered locale.
+func RegisterLocale(locale *Locale) {
+	localesMu.Lock()
+	defer localesMu.Unlock()
+
+	separator := `,\s*`
+	if locale.And != "" {
+		separator += `|\s+` + regexp.QuoteMeta(locale.And) + `\s+`
+	}
+	locale.listSeparator = regexp.MustCompile(separator)
+
+	replaced := false
+	for i, registered := range locales {
+		if registered.Name == locale.Name {
+			locales[i] = locale
+			replaced = true
+		}
+	}
+	if !replaced {
+		locales = append(locales, locale)
+	}
+
+	grammars = make(map[string]*headerGrammar)
+}
+
+// LookupLocale returns the registered locale with the given name, or nil
+func LookupLocale(name string) *Locale {
+	localesMu.RLock()
+	defer localesMu.RUnlock()
+
+	for _, locale := range locales {
+		if locale.Name == name {
+			return locale
+		}
+	}
+	return nil
+}
+
+// Locales returns the registered locales in registration order
+func Locales() []*Locale {
+	localesMu.RLock()
+	defer localesMu.RUnlock()
+
+	return append([]*Locale(nil), locales...)
+}
+
+// canonicalMarker lowercases an AM/PM marker and drops its dots and spaces,
+// so "a. m.", "A.M." and "am" compare equal
+func canonicalMarker(marker string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(marker) {
+		switch r {
+		case '.', ' ', '\u00A0', '\u202F':
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// addMarkers records the meaning of the markers of a locale, keeping the
+// meaning given by locales registered earlier
+func addMarkers(table map[string]string, locale *Locale) {
+	for meaning, markers := range map[string][]string{"AM": locale.AM, "PM": locale.PM} {
+		for _, marker := range markers {
+			key := canonicalMarker(marker)
+			if _, ok := table[key]; !ok {
+				table[key] = meaning
+			}
+		}
+	}
+}
+
+// markerAlternatives returns the canonical forms of the given markers
+// without duplicates, longest first
+func markerAlternatives(markers []string) []string {
+	seen := make(map[string]bool)
+	var alternatives []string
+
+	for _, marker := range markers {
+		key := canonicalMarker(marker)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		alternatives = append(alternatives, key)
+	}
+
+	sort.SliceStable(alternatives, func(i, j int) bool {
+		a, b := utf8.RuneCountInString(alternatives[i]), utf8.RuneCountInString(alternatives[j])
+		if a != b {
+			return a > b
+		}
+		return len(alternatives[i]) > len(alternatives[j])
+	})
+	return alternatives
+}
+
+// grammarFor returns the header grammar for the named locale,
+// or for all registered locales when none is named
+func grammarFor(name string) (*headerGrammar, error) {
+	localesMu.RLock()
+	grammar, ok := grammars[name]
+	localesMu.RUnlock()
+	if ok {
+		return grammar, nil
+	}
+
+	var selected []*Locale
+	if name == "" {
+		selected = Locales()
+	} else if locale := LookupLocale(name); locale != nil {
+		selected = []*Locale{locale}
+	} else {
+		return nil, ErrUnknownLocale
+	}
+
+	grammar = newHeaderGrammar(selected)
+
+	localesMu.Lock()
+	grammars[name] = grammar
+	localesMu.Unlock()
+
+	return grammar, nil
+}
+
+// DetectLocale guesses the locale a chat was exported with from its AM/PM
+// markers and system messages. It returns nil when nothing in the chat is
+// specific to a locale, e.g. for 24-hour chats without system messages.
+func DetectLocale(content string) *Locale {
+	grammar, _ := grammarFor("")
+	candidates := Locales()
+	scores := make([]int, len(candidates))
+	markers := make([]map[string]string, len(candidates))
+	for i, locale := range candidates {
+		markers[i] = make(map[string]string)
+		addMarkers(markers[i], locale)
+	}
+
+	lines := newLineReader(strings.NewReader(content))
+	for {
+		line, err := lines.next()
+		if err != nil {
+			break
+		}
+
+		header, ok := grammar.matchLine(line.text)
+		if !ok {
+			continue
+		}
+
+		for i, locale := range candidates {
+			if _, ok := markers[i][canonicalMarker(header.ampm)]; ok && header.ampm != "" {
+				scores[i]++
+			}
+			if header.system && parseSystemEvent(line.text[header.bodyStart:], []*Locale{locale}) != nil {
+				scores[i]++
+			}
+		}
+	}
+
+	var best *Locale
+	bestScore := 0
+	for i, score := range scores {
+		if score > bestScore {
+			best, bestScore = candidates[i], score
+		}
+	}
+	return best
+}
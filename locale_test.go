@@ -0,0 +1,116 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestLocales tests parsing chats exported in other languages
+func TestLocales(t *testing.T) {
+	t.Run("AM/PM markers", func(t *testing.T) {
+		tests := []struct {
+			line string
+			hour int
+		}{
+			{"13/6/18, 1:55 nachm. - a: m", 13},
+			{"13/6/18, 11:55 vorm. - a: m", 11},
+			{"13/6/18, 1:55 p. m. - a: m", 13},
+			{"13/6/18, 12:05 a. m. - a: m", 0},
+			{"[13/6/18, 1:55:00 PM] a: m", 13},
+			{"2018. 6. 13. 오후 1:55 - a: m", 13},
+			{"2018/06/13 午前9:05 - a: m", 9},
+			{"2018/6/13 下午3:04 - a: m", 15},
+			{"13/6/18, 3:04 م - a: m", 15},
+			{"13.06.2018 03:04 ÖS - a: m", 15},
+			{"13/6/18, 3:04 μ.μ. - a: m", 15},
+		}
+
+		for _, test := range tests {
+			messages, err := ParseString(test.line, nil)
+			if err != nil {
+				t.Errorf("Failed to parse %q: %v", test.line, err)
+				continue
+			}
+			if len(messages) != 1 {
+				t.Errorf("Expected 1 message for %q, got %d", test.line, len(messages))
+				continue
+			}
+
+			message := messages[0]
+			if message.Date.Hour() != test.hour || message.Date.Day() != 13 || message.Date.Month() != 6 {
+				t.Errorf("Expected 13 June at %d hours for %q, got %v", test.hour, test.line, message.Date)
+			}
+			if message.Author == nil || *message.Author != "a" || message.Message != "m" {
+				t.Errorf("Expected author 'a' and message 'm' for %q, got %v %q", test.line, message.Author, message.Message)
+			}
+		}
+	})
+
+	t.Run("Forced locale", func(t *testing.T) {
+		content := "13/6/18, 1:55 nachm. - a: m"
+
+		messages, err := ParseString(content, &ParseStringOptions{Locale: "ko"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(messages) != 1 || messages[0].Date.Hour() != 1 {
+			t.Errorf("Expected German markers to be ignored for Korean, got %v", messages)
+		}
+
+		if _, err := ParseString(content, &ParseStringOptions{Locale: "xx"}); !errors.Is(err, ErrUnknownLocale) {
+			t.Errorf("Expected ErrUnknownLocale, got %v", err)
+		}
+	})
+
+	t.Run("Localized system events", func(t *testing.T) {
+		content := "13.06.18, 21:25 - Anna hat die Gruppe „Urlaub“ erstellt\n" +
+			"13.06.18, 21:26 - Anna hat Bob und Carl hinzugefügt"
+
+		messages, err := ParseString(content, &ParseStringOptions{Locale: "de"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if event := messages[0].Event; event == nil || event.Type != SystemEventGroupCreated || event.Value != "Urlaub" {
+			t.Errorf("Expected group created event, got %+v", event)
+		}
+		if event := messages[1].Event; event == nil || event.Type != SystemEventMemberAdded || len(event.Targets) != 2 {
+			t.Errorf("Expected member added event with two targets, got %+v", event)
+		}
+	})
+
+	t.Run("DetectLocale", func(t *testing.T) {
+		tests := []struct {
+			content string
+			expect  string
+		}{
+			{"13/6/18, 1:55 nachm. - a: m\n13/6/18, 1:56 nachm. - b: m", "de"},
+			{"2018. 6. 13. 오후 1:55 - a: m", "ko"},
+			{"13/6/18, 1:55 PM - a: m", "en"},
+			{"13/6/18, 13:55 - Ana salió del grupo", "es"},
+		}
+
+		for _, test := range tests {
+			locale := DetectLocale(test.content)
+			if locale == nil || locale.Name != test.expect {
+				t.Errorf("DetectLocale(%q) = %v, want %q", test.content, locale, test.expect)
+			}
+		}
+
+		if locale := DetectLocale("13/6/18, 13:55 - a: m"); locale != nil {
+			t.Errorf("Expected no locale for a 24-hour chat, got %q", locale.Name)
+		}
+	})
+
+	t.Run("RegisterLocale", func(t *testing.T) {
+		RegisterLocale(&Locale{Name: "test", AM: []string{"mañ."}, PM: []string{"tard."}})
+
+		messages, err := ParseString("13/6/18, 1:55 tard. - a: m", &ParseStringOptions{Locale: "test"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(messages) != 1 || messages[0].Date.Hour() != 13 {
+			t.Errorf("Expected a message at 13 hours, got %v", messages)
+		}
+	})
+}
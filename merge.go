@@ -0,0 +1,327 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultMergeTolerance is the largest difference between the dates of a
+// message in two exports when MergeOptions has none. Android exports drop
+// the seconds that iOS exports keep.
+const DefaultMergeTolerance = time.Minute
+
+type MergeOptions struct {
+	// Tolerance is the largest difference between the dates of a message
+	// in two exports for them to be taken for the same message
+	Tolerance time.Duration `json:"tolerance"`
+}
+
+type ConflictKind string
+
+const (
+	// ConflictOwner reports that the owner of an export, written "You",
+	// matches messages of several people in the other exports. The most
+	// matched is chosen.
+	ConflictOwner ConflictKind = "owner"
+	// ConflictEdited and ConflictDeleted report a message edited or deleted
+	// in one export only. The edited or deleted version is kept.
+	ConflictEdited  ConflictKind = "edited"
+	ConflictDeleted ConflictKind = "deleted"
+)
+
+// Conflict reports a disagreement between exports found by Merge. History is
+// the index of the export being merged when it was found, and Versions the
+// kept version of the message first, then the dropped one.
+type Conflict struct {
+	Kind     ConflictKind `json:"kind"`
+	History  int          `json:"history"`
+	Message  string       `json:"message"`
+	Versions []Message    `json:"versions,omitempty"`
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("history %d: %s", c.History, c.Message)
+}
+
+type MergeResult struct {
+	Messages []Message `json:"messages"` // sorted by date
+	// Owners are, by history, the names found for the owner written "You",
+	// empty when the history has no such messages or none matched
+	Owners    []string   `json:"owners"`
+	Conflicts []Conflict `json:"conflicts"`
+}
+
+// mergeEntry is a message being merged, with its duplicate key
+type mergeEntry struct {
+	message Message
+	key     string
+}
+
+// Merge merges exports of the same chat into one history without
+// duplicates. A message of an export is a duplicate of one of the exports
+// before it with the same author and text, or the same attachment caption,
+// or the same system event, dated within the tolerance. The duplicate found
+// first is kept, taking the dates with seconds and the attachment of the
+// other when it lacks them. The owner of an export written "You", or in the
+// language of the export, is renamed after the author of the same messages in
+// the other exports. Attachments only match when parsed with
+// ParseAttachments.
+func Merge(histories [][]Message, opts *MergeOptions) *MergeResult {
+	if opts == nil {
+		opts = &MergeOptions{}
+	}
+	tolerance := opts.Tolerance
+	if tolerance <= 0 {
+		tolerance = DefaultMergeTolerance
+	}
+
+	result := &MergeResult{Owners: make([]string, len(histories))}
+	aliases := ownerAliases()
+	for i := range histories {
+		result.Owners[i] = findOwner(histories, i, aliases, tolerance, result)
+	}
+
+	var merged []mergeEntry
+	for h, history := range histories {
+		rename := func(name string) string {
+			if aliases[name] && result.Owners[h] != "" {
+				return result.Owners[h]
+			}
+			return name
+		}
+
+		entries := make([]mergeEntry, len(history))
+		for i, message := range history {
+			if message.Author != nil {
+				author := rename(*message.Author)
+				message.Author = &author
+			}
+			entries[i] = mergeEntry{message: message, key: mergeKey(message, rename)}
+		}
+
+		if h == 0 {
+			merged = entries
+		} else {
+			merged = mergeHistory(merged, entries, h, tolerance, result)
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].message.Date.Before(merged[j].message.Date)
+	})
+	result.Messages = make([]Message, len(merged))
+	for i, entry := range merged {
+		result.Messages[i] = entry.message
+	}
+	return result
+}
+
+// ownerAliases returns the words naming the owner in the registered locales
+func ownerAliases() map[string]bool {
+	aliases := make(map[string]bool)
+	for _, locale := range Locales() {
+		for _, word := range locale.Owner {
+			aliases[word] = true
+		}
+	}
+	return aliases
+}
+
+// findOwner returns the author most often found in the other histories for
+// the messages of a history written by its owner alias, and reports a
+// conflict if several were found
+func findOwner(histories [][]Message, h int, aliases map[string]bool, tolerance time.Duration, result *MergeResult) string {
+	keep := func(name string) string { return name }
+	// others holds the messages of the other histories by content
+	others := make(map[string][]*Message)
+	for j, history := range histories {
+		if j == h {
+			continue
+		}
+		for i := range history {
+			if message := &history[i]; message.Author != nil && !aliases[*message.Author] {
+				content := mergeContent(*message, keep)
+				others[content] = append(others[content], message)
+			}
+		}
+	}
+
+	var alias string
+	votes := make(map[string]int)
+	for _, message := range histories[h] {
+		if message.Author == nil || !aliases[*message.Author] {
+			continue
+		}
+		alias = *message.Author
+		// Deleted messages all look the same
+		if message.Deleted {
+			continue
+		}
+		for _, other := range others[mergeContent(message, keep)] {
+			if absDuration(other.Date.Sub(message.Date)) <= tolerance {
+				votes[*other.Author]++
+				break
+			}
+		}
+	}
+
+	var names []string
+	for name := range votes {
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if votes[names[i]] != votes[names[j]] {
+			return votes[names[i]] > votes[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	if len(names) > 1 {
+		counts := make([]string, len(names))
+		for i, name := range names {
+			counts[i] = fmt.Sprintf("%s (%d)", name, votes[name])
+		}
+		result.Conflicts = append(result.Conflicts, Conflict{
+			Kind:    ConflictOwner,
+			History: h,
+			Message: fmt.Sprintf("%q matches messages of %s, chose %s", alias, strings.Join(counts, ", "), names[0]),
+		})
+	}
+	return names[0]
+}
+
+// mergeKey returns what a message must share with its duplicates besides
+// its date: its author and content, with names renamed
+func mergeKey(message Message, rename func(string) string) string {
+	author := ""
+	if message.Author != nil {
+		author = rename(*message.Author)
+	}
+	return author + "\x00" + mergeContent(message, rename)
+}
+
+// mergeContent returns the content of a message compared by Merge: its
+// text, its attachment caption or its system event
+func mergeContent(message Message, rename func(string) string) string {
+	switch {
+	case message.Deleted:
+		return "deleted"
+	case message.Event != nil:
+		names := make([]string, len(message.Event.Targets))
+		for i, target := range message.Event.Targets {
+			names[i] = rename(target)
+		}
+		actor := ""
+		if message.Event.Actor != nil {
+			actor = rename(*message.Event.Actor)
+		}
+		return strings.Join([]string{"event", string(message.Event.Type), actor, strings.Join(names, ","), message.Event.Value}, "\x00")
+	case message.Attachment != nil:
+		return "attachment\x00" + message.Attachment.Caption
+	}
+	return "text\x00" + trimMarks(message.Message)
+}
+
+// mergeHistory merges the entries of a history into the merged entries of the
+// histories before it
+func mergeHistory(merged []mergeEntry, entries []mergeEntry, h int, tolerance time.Duration, result *MergeResult) []mergeEntry {
+	byKey := make(map[string][]int)
+	byAuthor := make(map[string][]int)
+	for i, entry := range merged {
+		byKey[entry.key] = append(byKey[entry.key], i)
+		if author := entry.message.Author; author != nil {
+			byAuthor[*author] = append(byAuthor[*author], i)
+		}
+	}
+	matched := make([]bool, len(merged))
+
+	// closest returns the unmatched merged entry among candidates closest in
+	// date to a message, within the tolerance
+	closest := func(candidates []int, message *Message, accept func(*Message) bool) int {
+		best := -1
+		var bestDistance time.Duration
+		for _, i := range candidates {
+			distance := absDuration(merged[i].message.Date.Sub(message.Date))
+			if matched[i] || distance > tolerance || !accept(&merged[i].message) {
+				continue
+			}
+			if best < 0 || distance < bestDistance {
+				best, bestDistance = i, distance
+			}
+		}
+		return best
+	}
+
+	var unmatched []mergeEntry
+	for _, entry := range entries {
+		i := closest(byKey[entry.key], &entry.message, func(*Message) bool { return true })
+		if i < 0 {
+			unmatched = append(unmatched, entry)
+			continue
+		}
+		matched[i] = true
+		completeDuplicate(&merged[i].message, &entry.message)
+	}
+
+	// A message left alone may be another version of a message edited or
+	// deleted in one of the exports
+	for _, entry := range unmatched {
+		message := &entry.message
+		if message.Author == nil || message.IsSystem {
+			merged = append(merged, entry)
+			continue
+		}
+
+		i := closest(byAuthor[*message.Author], message, func(other *Message) bool {
+			return !other.IsSystem && (other.Edited || other.Deleted || message.Edited || message.Deleted)
+		})
+		if i < 0 {
+			merged = append(merged, entry)
+			continue
+		}
+		matched[i] = true
+
+		kept, dropped := merged[i].message, *message
+		if dropped.Deleted || dropped.Edited && !kept.Deleted {
+			kept, dropped = dropped, kept
+			merged[i] = entry
+		}
+		kind, verb := ConflictEdited, "edited"
+		if kept.Deleted {
+			kind, verb = ConflictDeleted, "deleted"
+		}
+		result.Conflicts = append(result.Conflicts, Conflict{
+			Kind:     kind,
+			History:  h,
+			Message:  fmt.Sprintf("message of %s at %s %s in one export only", *kept.Author, kept.Date.Format("2006-01-02 15:04:05"), verb),
+			Versions: []Message{kept, dropped},
+		})
+	}
+
+	return merged
+}
+
+// completeDuplicate takes from a duplicate what a kept message lacks: the
+// seconds of its date and its attachment
+func completeDuplicate(kept *Message, duplicate *Message) {
+	if kept.Date.Second() == 0 && duplicate.Date.Second() != 0 {
+		kept.Date = duplicate.Date
+		kept.WallClock = duplicate.WallClock
+	}
+	if kept.Attachment != nil && kept.Attachment.Omitted && duplicate.Attachment != nil && !duplicate.Attachment.Omitted {
+		kept.Attachment = duplicate.Attachment
+	}
+}
+
+// absDuration returns the absolute value of a duration
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
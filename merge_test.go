@@ -0,0 +1,123 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+// TestMerge tests merging overlapping exports
+func TestMerge(t *testing.T) {
+	// Exported from the phone of Anna, on Android
+	first := "11/06/2018, 09:00 - You created group \"Trip\"\n" +
+		"11/06/2018, 09:01 - You: Where do we go?\n" +
+		"11/06/2018, 09:02 - Bob: The beach\n" +
+		"11/06/2018, 09:03 - You: Great\n" +
+		"11/06/2018, 09:05 - Bob: Bring towels\n" +
+		"11/06/2018, 09:06 - You: See you"
+	// Exported later from the phone of Bob, on iOS
+	second := "[6/11/18, 9:00:10 AM] Trip: \u200EAnna created group “Trip”\n" +
+		"[6/11/18, 9:01:20 AM] Anna: Where do we go?\n" +
+		"[6/11/18, 9:02:30 AM] You: The beach\n" +
+		"[6/11/18, 9:03:40 AM] Anna: Great! \u200E<This message was edited>\n" +
+		"[6/11/18, 9:05:00 AM] You: \u200EYou deleted this message\n" +
+		"[6/11/18, 9:07:00 AM] Carl: Late"
+
+	daysFirst := false
+	histories := make([][]Message, 2)
+	var err error
+	if histories[0], err = ParseString(first, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if histories[1], err = ParseString(second, &ParseStringOptions{DaysFirst: &daysFirst}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	result := Merge(histories, nil)
+
+	t.Run("owners", func(t *testing.T) {
+		if len(result.Owners) != 2 || result.Owners[0] != "Anna" || result.Owners[1] != "Bob" {
+			t.Errorf("Expected Anna and Bob, got %v", result.Owners)
+		}
+	})
+
+	t.Run("messages", func(t *testing.T) {
+		expect := []struct {
+			author  string
+			message string
+		}{
+			{"", "You created group \"Trip\""},
+			{"Anna", "Where do we go?"},
+			{"Bob", "The beach"},
+			{"Anna", "Great!"},
+			{"Bob", ""},
+			{"Anna", "See you"},
+			{"Carl", "Late"},
+		}
+		if len(result.Messages) != len(expect) {
+			t.Fatalf("Expected %d messages, got %d: %+v", len(expect), len(result.Messages), result.Messages)
+		}
+		for i, e := range expect {
+			message := result.Messages[i]
+			author := ""
+			if message.Author != nil {
+				author = *message.Author
+			}
+			if author != e.author || message.Message != e.message {
+				t.Errorf("Message %d: expected %s: %q, got %s: %q", i, e.author, e.message, author, message.Message)
+			}
+		}
+
+		if date := result.Messages[1].Date; date.Second() != 20 {
+			t.Errorf("Expected the date with seconds, got %v", date)
+		}
+		if !result.Messages[3].Edited || !result.Messages[4].Deleted {
+			t.Errorf("Expected the edited and deleted versions kept")
+		}
+	})
+
+	t.Run("conflicts", func(t *testing.T) {
+		if len(result.Conflicts) != 2 {
+			t.Fatalf("Expected 2 conflicts, got %+v", result.Conflicts)
+		}
+		edited, deleted := result.Conflicts[0], result.Conflicts[1]
+		if edited.Kind != ConflictEdited || edited.History != 1 || edited.Versions[1].Message != "Great" {
+			t.Errorf("Unexpected conflict %+v", edited)
+		}
+		if deleted.Kind != ConflictDeleted || deleted.Versions[1].Message != "Bring towels" {
+			t.Errorf("Unexpected conflict %+v", deleted)
+		}
+		if deleted.String() != "history 1: message of Bob at 2018-06-11 09:05:00 deleted in one export only" {
+			t.Errorf("Unexpected description %q", deleted.String())
+		}
+	})
+
+	t.Run("owner conflicts", func(t *testing.T) {
+		third, err := ParseString("11/06/2018, 09:01 - You: Where do we go?\n"+
+			"11/06/2018, 09:02 - You: The beach", nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		result := Merge([][]Message{histories[0], histories[1], third}, nil)
+		var conflict *Conflict
+		for i := range result.Conflicts {
+			if result.Conflicts[i].Kind == ConflictOwner {
+				conflict = &result.Conflicts[i]
+			}
+		}
+		if conflict == nil || conflict.History != 2 || result.Owners[2] != "Anna" {
+			t.Errorf("Expected an owner conflict resolved to Anna, got %+v and %v", result.Conflicts, result.Owners)
+		}
+	})
+
+	t.Run("tolerance", func(t *testing.T) {
+		result := Merge(histories, &MergeOptions{Tolerance: 10 * time.Second})
+		if len(result.Messages) <= 7 {
+			t.Errorf("Expected fewer duplicates found, got %d messages", len(result.Messages))
+		}
+
+		if result := Merge([][]Message{histories[0], histories[0]}, nil); len(result.Messages) != len(histories[0]) {
+			t.Errorf("Expected a history merged with itself unchanged, got %d messages", len(result.Messages))
+		}
+	})
+}
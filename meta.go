@@ -1,7 +1,11 @@
 package parser
 
 import (
+	"regexp"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 func GetAuthorsFromMessages(messages *[]Message) []string {
@@ -32,3 +36,140 @@ func GetFirstAndLastMessageDates(messages *[]Message) (*time.Time, *time.Time) {
 	lastDate := (*messages)[len(*messages)-1].Date
 	return &firstDate, &lastDate
 }
+
+// regexLink matches the links counted by GetStats
+var regexLink = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+
+// AuthorStats are the statistics of the messages of one author, or of all
+// participants. Words and characters are counted in the message text, or in
+// the caption of attachments when the chat was parsed with
+// ParseAttachments.
+type AuthorStats struct {
+	Messages   int                    `json:"messages"`
+	Deleted    int                    `json:"deleted"`
+	Words      int                    `json:"words"`
+	Characters int                    `json:"characters"`
+	Media      map[AttachmentKind]int `json:"media"`
+	Links      int                    `json:"links"`
+	// Emoji counts each emoji code point, so the parts of a combined emoji
+	// are counted apart
+	Emoji         map[string]int `json:"emoji"`
+	AverageLength float64        `json:"averageLength"` // characters per message
+	FirstMessage  time.Time      `json:"firstMessage"`
+	LastMessage   time.Time      `json:"lastMessage"`
+	// LongestSilence is the longest time between two consecutive messages
+	LongestSilence time.Duration `json:"longestSilence"`
+	ActiveDays     int           `json:"activeDays"`
+
+	days map[string]bool
+}
+
+// Stats are the statistics of the messages of a chat, overall and by author.
+// System messages are not counted.
+type Stats struct {
+	Overall AuthorStats             `json:"overall"`
+	Authors map[string]*AuthorStats `json:"authors"`
+}
+
+// countWords counts the words of a text: the runs of characters between
+// spaces holding at least a letter or a digit
+func countWords(text string) int {
+	words := 0
+	for _, field := range strings.FieldsFunc(text, unicode.IsSpace) {
+		if strings.IndexFunc(field, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsNumber(r) }) >= 0 {
+			words++
+		}
+	}
+	return words
+}
+
+// isEmoji reports whether a code point is an emoji, leaving out skin tone
+// modifiers and the joiners of combined emoji
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F3FB && r <= 0x1F3FF:
+		return false
+	case r >= 0x1F000 && r <= 0x1FAFF, r >= 0x2600 && r <= 0x27BF, r >= 0x2B00 && r <= 0x2BFF:
+		return true
+	}
+	return false
+}
+
+// add counts a message, with the text to count and the time of the
+// previous message
+func (s *AuthorStats) add(message Message, text string, previous time.Time) {
+	if s.Messages == 0 || message.Date.Before(s.FirstMessage) {
+		s.FirstMessage = message.Date
+	}
+	if message.Date.After(s.LastMessage) {
+		s.LastMessage = message.Date
+	}
+	if !previous.IsZero() && message.Date.Sub(previous) > s.LongestSilence {
+		s.LongestSilence = message.Date.Sub(previous)
+	}
+
+	s.Messages++
+	if message.Deleted {
+		s.Deleted++
+	}
+	s.Words += countWords(text)
+	s.Characters += utf8.RuneCountInString(text)
+	s.Links += len(regexLink.FindAllStringIndex(text, -1))
+	for _, r := range text {
+		if isEmoji(r) {
+			s.Emoji[string(r)]++
+		}
+	}
+	if message.Attachment != nil {
+		s.Media[message.Attachment.Kind]++
+	}
+
+	day := message.Date.Format("2006-01-02")
+	if !s.days[day] {
+		s.days[day] = true
+		s.ActiveDays++
+	}
+	s.AverageLength = float64(s.Characters) / float64(s.Messages)
+}
+
+func newAuthorStats() *AuthorStats {
+	return &AuthorStats{
+		Media: make(map[AttachmentKind]int),
+		Emoji: make(map[string]int),
+		days:  make(map[string]bool),
+	}
+}
+
+// GetStats computes the statistics of the messages of a chat, overall and by
+// author, with the days of the dates in their own time zone
+func GetStats(messages *[]Message) *Stats {
+	stats := &Stats{Authors: make(map[string]*AuthorStats)}
+	overall := newAuthorStats()
+
+	var previous time.Time
+	previousByAuthor := make(map[string]time.Time)
+	for _, message := range *messages {
+		if message.IsSystem || message.Author == nil {
+			continue
+		}
+
+		text := message.Message
+		if message.Attachment != nil {
+			text = message.Attachment.Caption
+		}
+
+		author := stats.Authors[*message.Author]
+		if author == nil {
+			author = newAuthorStats()
+			stats.Authors[*message.Author] = author
+		}
+		author.add(message, text, previousByAuthor[*message.Author])
+		overall.add(message, text, previous)
+
+		previous = message.Date
+		previousByAuthor[*message.Author] = message.Date
+	}
+
+	stats.Overall = *overall
+	return stats
+}
@@ -0,0 +1,50 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+// TestGetStats tests the statistics of messages
+func TestGetStats(t *testing.T) {
+	content := "11/06/2018, 09:00 - Anna created group \"Trip\"\n" +
+		"11/06/2018, 09:05 - Anna: Where do we go? 🏖️🏖️\n" +
+		"11/06/2018, 09:06 - Bob: IMG-20180611-WA0001.jpg (file attached)\n" +
+		"nice spot\n" +
+		"13/06/2018, 21:25 - Anna: see https://example.com and www.example.org :)\n" +
+		"14/06/2018, 08:00 - Bob: This message was deleted\n" +
+		"14/06/2018, 08:30 - Bob: <Media omitted>"
+
+	messages, err := ParseString(content, &ParseStringOptions{ParseAttachments: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	stats := GetStats(&messages)
+
+	anna := stats.Authors["Anna"]
+	if anna == nil || anna.Messages != 2 || anna.Words != 8 || anna.Links != 2 || anna.Emoji["🏖"] != 2 || anna.ActiveDays != 2 {
+		t.Errorf("Unexpected stats for Anna: %+v", anna)
+	}
+	if anna.LongestSilence != 60*time.Hour+20*time.Minute {
+		t.Errorf("Expected Anna's longest silence to be 60h20m, got %v", anna.LongestSilence)
+	}
+
+	bob := stats.Authors["Bob"]
+	if bob == nil || bob.Messages != 3 || bob.Deleted != 1 || bob.Words != 2 || bob.Media[AttachmentImage] != 1 || bob.Media[AttachmentUnknown] != 1 {
+		t.Errorf("Unexpected stats for Bob: %+v", bob)
+	}
+	if bob.AverageLength != 3 {
+		t.Errorf("Expected Bob's average length to be 3, got %v", bob.AverageLength)
+	}
+
+	overall := stats.Overall
+	if overall.Messages != 5 || overall.Words != 10 || overall.ActiveDays != 3 || len(stats.Authors) != 2 {
+		t.Errorf("Unexpected overall stats: %+v", overall)
+	}
+	if !overall.FirstMessage.Equal(time.Date(2018, 6, 11, 9, 5, 0, 0, time.UTC)) || !overall.LastMessage.Equal(time.Date(2018, 6, 14, 8, 30, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected first and last messages %v %v", overall.FirstMessage, overall.LastMessage)
+	}
+	if overall.LongestSilence != 60*time.Hour+19*time.Minute {
+		t.Errorf("Expected the longest silence to be 60h19m, got %v", overall.LongestSilence)
+	}
+}
@@ -1,242 +1,484 @@
 package parser
 
 import (
+	"fmt"
 	"regexp"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 var (
-	sharedRegex           = `^(?:[\x{200E}\x{200F}])*\[?(\d{1,4}[-/.]\s?\d{1,4}[-/.]\s?\d{1,4})[,.]?\s\D*?(\d{1,2}[.:]\d{1,2}(?:[.:]\d{1,2})?)(?:\s([ap]\.?\s?m\.?))?\]?(?:\s-|:)?\s`
-	authorAndMessageRegex = `(.+?):\s((?s:.*))`
-	messageRegex          = `((?s:.*))`
-	regexParser           = regexp.MustCompile(`(?i)` + sharedRegex + authorAndMessageRegex)
-	regexParserSystem     = regexp.MustCompile(`(?i)` + sharedRegex + messageRegex)
-	regexAttachment       = regexp.MustCompile(`(?:\x{200E}|\x{200F})*(?:<.+:(.+)>|([\w-]+\.\w+)\s[(<].+[)>])`)
-	regexSplitTime        = regexp.MustCompile(`[:.]+`)
-	newlinesRegex         = regexp.MustCompile(`(?:\r\n|\r|\n)`)
+	regexAttachment = regexp.MustCompile(`(?:\x{200E}|\x{200F})*(?:<.+:(.+)>|([\w-]+\.\w+)\s[(<].+[)>])`)
+	regexSplitTime  = regexp.MustCompile(`[:.]+`)
 )
 
 func isNotNewFormatSystemMessage(message string) bool {
 	return strings.Count(message, "\u200E") != 1
 }
 
+// headerGrammar recognises the header of messages, the part holding the date,
+// time and author, for a set of locales
+type headerGrammar struct {
+	locales []*Locale
+	// alternatives are the canonical markers in the order they are tried
+	alternatives []string
+	markers      map[string]string
+}
+
+func newHeaderGrammar(locales []*Locale) *headerGrammar {
+	grammar := &headerGrammar{
+		locales: locales,
+		markers: make(map[string]string),
+	}
+
+	var markers []string
+	for _, locale := range locales {
+		markers = append(append(markers, locale.AM...), locale.PM...)
+		addMarkers(grammar.markers, locale)
+	}
+
+	grammar.alternatives = markerAlternatives(markers)
+
+	return grammar
+}
+
+// meaning returns "AM" or "PM" for a marker of the grammar's locales
+func (g *headerGrammar) meaning(marker string) string {
+	return g.markers[canonicalMarker(marker)]
+}
+
+// messageHeader holds the parts of the first line of a raw message
+type messageHeader struct {
+	system    bool
+	date      string
+	time      string
+	ampm      string // marker written before or after the time
+	author    string
+	bodyStart int // byte offset of the message text
+}
+
+// classify reports whether a line starts a new message and, if so, whether
+// that message is a system message. The header is returned as well unless
+// the lines after it could change it.
+func (g *headerGrammar) classify(line string) (isHeader bool, system bool, header *messageHeader) {
+	result := g.lex(line)
+	if !result.isUser && !result.isSystem {
+		return false, false, nil
+	}
+	system = !(result.isUser && isNotNewFormatSystemMessage(line))
+	if !result.truncated {
+		header = &result.user
+		if system {
+			header = &result.system
+		}
+	}
+	return true, system, header
+}
+
+// match reads the header of the kind of the raw message, and returns false
+// if the header is not recognised
+func (g *headerGrammar) match(rawMsg RawMessage) (messageHeader, bool) {
+	if rawMsg.header != nil {
+		return *rawMsg.header, true
+	}
+
+	result := g.lex(rawMsg.Msg)
+	if rawMsg.System {
+		return result.system, result.isSystem
+	}
+	return result.user, result.isUser
+}
+
+// matchLine classifies and matches a single line
+func (g *headerGrammar) matchLine(line string) (messageHeader, bool) {
+	isHeader, system, header := g.classify(line)
+	if !isHeader {
+		return messageHeader{}, false
+	}
+	if header != nil {
+		return *header, true
+	}
+	return g.match(RawMessage{System: system, Msg: line})
+}
+
+// parseContext holds the options of a parse along with what is derived from
+// them, and collects the diagnostics of the parse
+type parseContext struct {
+	options     ParseStringOptions
+	grammar     *headerGrammar
+	diagnostics []Diagnostic
+	err         error // first fatal diagnostic in strict mode
+}
+
+func newParseContext(options *ParseStringOptions) (*parseContext, error) {
+	ctx := &parseContext{}
+	if options != nil {
+		ctx.options = *options
+	}
+
+	grammar, err := grammarFor(ctx.options.Locale)
+	if err != nil {
+		return nil, err
+	}
+	ctx.grammar = grammar
+
+	return ctx, nil
+}
+
+// messageAssembler groups lines into raw messages. Lines that don't start a
+// new message are appended to the previous one, and lines before the first
+// message are dropped.
+type messageAssembler struct {
+	ctx     *parseContext
+	current *RawMessage
+}
+
+// push adds a line and returns the previous raw message once the line starts
+// a new one, since only then is the previous message known to be complete
+func (a *messageAssembler) push(line sourceLine) (RawMessage, bool) {
+	isHeader, system, header := a.ctx.grammar.classify(line.text)
+	if !isHeader {
+		// If the line has no header, it's part of a previous message
+		if a.current != nil {
+			a.current.Msg += "\n" + line.text
+		} else if strings.TrimSpace(line.text) != "" {
+			a.ctx.report(Diagnostic{
+				Kind:    DiagnosticDroppedLine,
+				Line:    line.number,
+				Offset:  line.offset,
+				Message: "text before the first message: " + strconv.Quote(line.text),
+			})
+		}
+		return RawMessage{}, false
+	}
+
+	previous := a.current
+	a.current = &RawMessage{
+		System: system,
+		Msg:    line.text,
+		Line:   line.number,
+		Offset: line.offset,
+		header: header,
+	}
+	if previous == nil {
+		return RawMessage{}, false
+	}
+	return *previous, true
+}
+
+// flush returns the message still being assembled, if any
+func (a *messageAssembler) flush() (RawMessage, bool) {
+	if a.current == nil {
+		return RawMessage{}, false
+	}
+	last := *a.current
+	a.current = nil
+	return last, true
+}
+
 // makeArrayOfMessages takes an array of lines and detects multiline messages
-func makeArrayOfMessages(lines []string) []RawMessage {
+func makeArrayOfMessages(lines []sourceLine, ctx *parseContext) []RawMessage {
 	var result []RawMessage
+	assembler := messageAssembler{ctx: ctx}
 
 	for _, line := range lines {
-		if !regexParser.MatchString(line) && !regexParserSystem.MatchString(line) {
-			// If the line doesn't match either regex pattern, it's part of a previous message
-			if len(result) > 0 {
-				lastIndex := len(result) - 1
-				prevMsg := result[lastIndex]
-				result[lastIndex] = RawMessage{
-					System: prevMsg.System,
-					Msg:    prevMsg.Msg + "\n" + line,
-				}
-			}
-		} else {
-			// This is a new message
-			if regexParser.MatchString(line) && isNotNewFormatSystemMessage(line) {
-				result = append(result, RawMessage{
-					System: false,
-					Msg:    line,
-				})
-			} else {
-				// It's a system message
-				result = append(result, RawMessage{
-					System: true,
-					Msg:    line,
-				})
-			}
+		if rawMsg, ok := assembler.push(line); ok {
+			result = append(result, rawMsg)
 		}
 	}
+	if rawMsg, ok := assembler.flush(); ok {
+		result = append(result, rawMsg)
+	}
 
 	return result
 }
 
 // parseMessageAttachment parses a message to extract attachment details
 func parseMessageAttachment(message string) *Attachment {
-	matches := regexAttachment.FindStringSubmatch(message)
+	matches := regexAttachment.FindStringSubmatchIndex(message)
 	if matches == nil {
 		return nil
 	}
+	group := func(i int) string {
+		if matches[2*i] < 0 {
+			return ""
+		}
+		return message[matches[2*i]:matches[2*i+1]]
+	}
 
-	var fileName string
-	if len(matches) > 1 && matches[1] != "" {
-		fileName = strings.TrimSpace(matches[1])
-	} else if len(matches) > 2 {
-		fileName = strings.TrimSpace(matches[2])
+	fileName := strings.TrimSpace(group(1))
+	if fileName == "" {
+		fileName = strings.TrimSpace(group(2))
 	}
 
-	return &Attachment{
+	attachment := &Attachment{
 		FileName: fileName,
 	}
+	attachment.describe(message[:matches[0]], message[matches[1]:])
+	return attachment
 }
 
-// parseMessages parses an array of raw messages into structured messages
-func parseMessages(messages []RawMessage, options ParseStringOptions) ([]Message, error) {
-	var result []Message
-	var allDates [][]int
+// dateComponents converts the date of a matched header into numbers, with the
+// year pushed to the end, for date format detection
+func dateComponents(header messageHeader) []int {
+	dateParts := orderDateComponents(header.date)
 
-	// First pass: collect date components for format detection and create message objects
-	for _, rawMsg := range messages {
-		var matches []string
+	components := make([]int, 3)
+	for i, part := range dateParts {
+		val, _ := strconv.Atoi(part)
+		components[i] = val
+	}
 
-		if rawMsg.System {
-			matches = regexParserSystem.FindStringSubmatch(rawMsg.Msg)
-		} else {
-			matches = regexParser.FindStringSubmatch(rawMsg.Msg)
-		}
+	return components
+}
 
-		if matches == nil {
-			continue
-		}
+// resolveDaysFirst applies the DaysFirst option, falling back to the given
+// detection result and finally to the days-first default
+func resolveDaysFirst(options ParseStringOptions, detected *bool) bool {
+	if options.DaysFirst != nil {
+		return *options.DaysFirst
+	}
+	if detected != nil {
+		return *detected
+	}
+	return true // Default assumption
+}
 
-		// Extract date components for format detection
-		dateStr := matches[1]
-		dateParts := orderDateComponents(dateStr)
+// buildMessage turns a matched raw message into a structured message
+func (ctx *parseContext) buildMessage(rawMsg RawMessage, header messageHeader, daysFirst bool) Message {
+	var message Message
 
-		dateComponents := make([]int, 3)
-		for i, part := range dateParts {
-			val, _ := strconv.Atoi(part)
-			dateComponents[i] = val
-		}
+	dateParts := orderDateComponents(header.date)
 
-		allDates = append(allDates, dateComponents)
+	var day, month, year string
+	if daysFirst {
+		day, month, year = dateParts[0], dateParts[1], dateParts[2]
+	} else {
+		month, day, year = dateParts[0], dateParts[1], dateParts[2]
+	}
 
-		// Create message objects with just author and text for now
-		var message Message
-		if rawMsg.System {
-			messageText := matches[4]
-			message = Message{
-				Author:  nil,
-				Message: messageText,
-			}
-		} else {
-			author := matches[4]
-			messageText := matches[5]
-			message = Message{
-				Author:  &author,
-				Message: messageText,
-			}
-		}
+	normalizedDate := normalizeDate(year, month, day)
+	year, month, day = normalizedDate[0], normalizedDate[1], normalizedDate[2]
 
-		result = append(result, message)
+	var normalizedTime string
+	if header.ampm != "" {
+		normalizedTime = normalizeTime(convertTime12to24(header.time, ctx.grammar.meaning(header.ampm)))
+	} else {
+		normalizedTime = normalizeTime(header.time)
 	}
 
-	// Determine if days come first
-	var daysFirst bool
-	if options.DaysFirst != nil {
-		daysFirst = *options.DaysFirst
+	timeParts := strings.Split(normalizedTime, ":")
+	hour, minute, second := timeParts[0], timeParts[1], timeParts[2]
+
+	yearInt, yearErr := strconv.Atoi(year)
+	monthInt, monthErr := strconv.Atoi(month)
+	dayInt, dayErr := strconv.Atoi(day)
+	hourInt, hourErr := strconv.Atoi(hour)
+	minuteInt, minuteErr := strconv.Atoi(minute)
+	secondInt, secondErr := strconv.Atoi(second)
+
+	if yearErr != nil || monthErr != nil || dayErr != nil || !isValidDate(yearInt, monthInt, dayInt) {
+		ctx.report(Diagnostic{
+			Kind:    DiagnosticInvalidDate,
+			Line:    rawMsg.Line,
+			Offset:  rawMsg.Offset,
+			Message: fmt.Sprintf("invalid date %q read as year %s, month %s, day %s", header.date, year, month, day),
+		})
+	}
+	if hourErr != nil || minuteErr != nil || secondErr != nil || !isValidTime(header.time, header.ampm != "", hourInt, minuteInt, secondInt) {
+		ctx.report(Diagnostic{
+			Kind:    DiagnosticInvalidTime,
+			Line:    rawMsg.Line,
+			Offset:  rawMsg.Offset,
+			Message: fmt.Sprintf("invalid time %q", strings.TrimSpace(header.time+" "+header.ampm)),
+		})
+	}
+
+	message.WallClock = time.Date(yearInt, time.Month(monthInt), dayInt, hourInt, minuteInt, secondInt, 0, time.UTC)
+	message.Date = message.WallClock
+	if ctx.options.Location != nil {
+		message.Date = ctx.localDate(rawMsg, message.WallClock)
+	}
+
+	// Use the full raw message to extract the complete message text, including newlines
+	message.Message = strings.TrimSuffix(rawMsg.Msg[header.bodyStart:], "\n")
+	if rawMsg.System {
+		message.IsSystem = true
 	} else {
-		daysFirstPtr := daysBeforeMonths(allDates)
-		if daysFirstPtr != nil {
-			daysFirst = *daysFirstPtr
-		} else {
-			daysFirst = true // Default assumption
-		}
+		author := header.author
+		message.Author = &author
+	}
+	ctx.markDeletedAndEdited(&message)
+	if message.IsSystem {
+		message.Event = parseSystemEvent(message.Message, ctx.grammar.locales)
 	}
 
-	// Second pass: add proper date objects and preserve full message content
-	for i, rawMsg := range messages {
-		if i >= len(result) {
-			break
+	// Add attachment if requested
+	if ctx.options.ParseAttachments {
+		message.Attachment = parseMessageAttachment(message.Message)
+		if message.Attachment == nil {
+			message.Attachment = parseOmittedMedia(message.Message, ctx.grammar.locales)
 		}
+	}
 
-		var matches []string
-		if rawMsg.System {
-			matches = regexParserSystem.FindStringSubmatch(rawMsg.Msg)
-		} else {
-			matches = regexParser.FindStringSubmatch(rawMsg.Msg)
-		}
+	if !message.IsSystem && !message.Deleted {
+		message.Payload = parsePayload(message.Message, message.Attachment)
+	}
 
-		if matches == nil {
-			continue
-		}
+	return message
+}
 
-		dateStr := matches[1]
-		timeStr := matches[2]
-		var ampmStr string
-		if len(matches) > 3 && matches[3] != "" {
-			ampmStr = matches[3]
+// encryptionNoticeWindow is the number of leading messages checked for the
+// end-to-end encryption notice, which is a system message even when it
+// looks like it has an author
+const encryptionNoticeWindow = 10
+
+// markEncryptionNotice turns the message at the given index into a system
+// message if it is the end-to-end encryption notice
+func markEncryptionNotice(index int, message *Message) {
+	if index < encryptionNoticeWindow && strings.Contains(message.Message, "end-to-end") {
+		message.Author = nil
+		message.IsSystem = true
+		if message.Event == nil {
+			message.Event = &SystemEvent{Type: SystemEventEncryption}
 		}
+	}
+}
 
-		dateParts := orderDateComponents(dateStr)
+// parseMessages parses an array of raw messages into structured messages
+func (ctx *parseContext) parseMessages(messages []RawMessage) ([]Message, error) {
+	var matched []RawMessage
+	var headers []messageHeader
+	var allDates [][]int
 
-		var day, month, year string
-		if daysFirst {
-			day, month, year = dateParts[0], dateParts[1], dateParts[2]
-		} else {
-			month, day, year = dateParts[0], dateParts[1], dateParts[2]
+	// First pass: collect date components for format detection
+	for _, rawMsg := range messages {
+		header, ok := ctx.grammar.match(rawMsg)
+		if !ok {
+			continue
 		}
 
-		normalizedDate := normalizeDate(year, month, day)
-		year, month, day = normalizedDate[0], normalizedDate[1], normalizedDate[2]
+		matched = append(matched, rawMsg)
+		headers = append(headers, header)
+		allDates = append(allDates, dateComponents(header))
+	}
 
-		var normalizedTime string
-		if ampmStr != "" {
-			normalizedTime = normalizeTime(convertTime12to24(timeStr, normalizeAMPM(ampmStr)))
-		} else {
-			normalizedTime = normalizeTime(timeStr)
+	// Determine if days come first
+	var detected *bool
+	if ctx.options.DaysFirst == nil {
+		detected = daysBeforeMonths(allDates)
+		if len(allDates) > 0 && checkAbove12(allDates) == nil {
+			ctx.reportAmbiguousDateOrder(resolveDaysFirst(ctx.options, detected), detected != nil)
 		}
+	}
+	daysFirst := resolveDaysFirst(ctx.options, detected)
+	if ctx.err != nil {
+		return nil, ctx.err
+	}
+
+	// Second pass: build messages with proper dates and full message content
+	workers := ctx.options.Concurrency
+	if workers < 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > 1 && len(matched) > 1 {
+		return ctx.buildMessagesConcurrently(matched, headers, daysFirst, workers)
+	}
 
-		timeParts := strings.Split(normalizedTime, ":")
-		hour, minute, second := timeParts[0], timeParts[1], timeParts[2]
-
-		yearInt, _ := strconv.Atoi(year)
-		monthInt, _ := strconv.Atoi(month)
-		dayInt, _ := strconv.Atoi(day)
-		hourInt, _ := strconv.Atoi(hour)
-		minuteInt, _ := strconv.Atoi(minute)
-		secondInt, _ := strconv.Atoi(second)
-
-		date := time.Date(yearInt, time.Month(monthInt), dayInt, hourInt, minuteInt, secondInt, 0, time.UTC)
-		result[i].Date = date
-
-		// Use the full raw message to extract the complete message text
-		if rawMsg.System {
-			prefixLen := len(matches[0]) - len(matches[4])
-			result[i].Message = strings.TrimSuffix(rawMsg.Msg[prefixLen:], "\n")
-		} else {
-			// Extract the full message text, including newlines
-			prefixLen := len(matches[0]) - len(matches[5])
-			result[i].Message = strings.TrimSuffix(rawMsg.Msg[prefixLen:], "\n")
+	var result []Message
+	for i, rawMsg := range matched {
+		message := ctx.buildMessage(rawMsg, headers[i], daysFirst)
+		if ctx.err != nil {
+			return nil, ctx.err
 		}
+		markEncryptionNotice(i, &message)
+		result = append(result, message)
+	}
 
-		// Add attachment if requested
-		if options.ParseAttachments {
-			if i < len(result) {
-				attachment := parseMessageAttachment(result[i].Message)
-				if attachment != nil {
-					result[i].Attachment = attachment
-				}
+	return result, nil
+}
+
+// buildMessagesConcurrently builds messages on several goroutines, each
+// taking a contiguous shard of them and collecting its own diagnostics,
+// which are then reported in message order
+func (ctx *parseContext) buildMessagesConcurrently(matched []RawMessage, headers []messageHeader, daysFirst bool, workers int) ([]Message, error) {
+	if workers > len(matched) {
+		workers = len(matched)
+	}
+
+	result := make([]Message, len(matched))
+	shards := make([]*parseContext, workers)
+	var wg sync.WaitGroup
+	for w := range shards {
+		shard := &parseContext{options: ctx.options, grammar: ctx.grammar}
+		shards[w] = shard
+		start, end := w*len(matched)/workers, (w+1)*len(matched)/workers
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// In strict mode a shard stops at its first fatal diagnostic
+			for i := start; i < end && shard.err == nil; i++ {
+				result[i] = shard.buildMessage(matched[i], headers[i], daysFirst)
+				markEncryptionNotice(i, &result[i])
 			}
+		}()
+	}
+	wg.Wait()
+
+	for _, shard := range shards {
+		for _, d := range shard.diagnostics {
+			ctx.report(d)
+		}
+		if ctx.err != nil {
+			return nil, ctx.err
 		}
 	}
+	return result, nil
+}
+
+// Parse parses a string containing a WhatsApp chat log, and reports the
+// problems found along with the messages
+func Parse(content string, options *ParseStringOptions) (*ParseResult, error) {
+	ctx, err := newParseContext(options)
+	if err != nil {
+		return nil, err
+	}
 
-	for i := range min(10, len(result)) {
-		if strings.Contains(result[i].Message, "end-to-end") {
-			result[i].Author = nil
+	var lines []sourceLine
+	reader := newLineReader(strings.NewReader(content))
+	for {
+		line, err := reader.next()
+		if err != nil {
+			break
 		}
+		lines = append(lines, line)
 	}
 
-	return result, nil
+	rawMessages := makeArrayOfMessages(lines, ctx)
+	if ctx.err != nil {
+		return nil, ctx.err
+	}
+	messages, err := ctx.parseMessages(rawMessages)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ParseResult{
+		Messages:    messages,
+		Diagnostics: ctx.diagnostics,
+	}, nil
 }
 
 // ParseString parses a string containing a WhatsApp chat log.
 // Returns an array of parsed messages.
 func ParseString(content string, options *ParseStringOptions) ([]Message, error) {
-	if options == nil {
-		defaultOptions := ParseStringOptions{
-			ParseAttachments: false,
-		}
-		options = &defaultOptions
+	result, err := Parse(content, options)
+	if err != nil {
+		return nil, err
 	}
-
-	lines := newlinesRegex.Split(content, -1)
-	rawMessages := makeArrayOfMessages(lines)
-	return parseMessages(rawMessages, *options)
+	return result.Messages, nil
 }
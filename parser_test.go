@@ -1,7 +1,11 @@
 package parser
 
 import (
+	"bytes"
+	"errors"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -344,6 +348,303 @@ Is everything alright?`
 
 }
 
+// TestParseReader tests the streaming parser against ParseString
+func TestParseReader(t *testing.T) {
+	for _, chatExample := range chatExamples {
+		fileContents, err := os.ReadFile(chatExample.filePath)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		t.Run("Same messages as ParseString "+chatExample.description, func(t *testing.T) {
+			expected, err := ParseString(string(fileContents), nil)
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+
+			var messages []Message
+			for message, err := range ParseReader(bytes.NewReader(fileContents), nil) {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				messages = append(messages, message)
+			}
+
+			if !reflect.DeepEqual(messages, expected) {
+				t.Errorf("Expected %v, got %v", expected, messages)
+			}
+		})
+	}
+
+	t.Run("Line endings", func(t *testing.T) {
+		content := "1/2/20, 10:00 - a: one\r\ntwo\rthree\n1/2/20, 10:01 - b: four\r\n"
+
+		var messages []Message
+		for message, err := range ParseReader(strings.NewReader(content), nil) {
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			messages = append(messages, message)
+		}
+
+		expected, _ := ParseString(content, nil)
+		if !reflect.DeepEqual(messages, expected) {
+			t.Errorf("Expected %v, got %v", expected, messages)
+		}
+	})
+
+	t.Run("Date order contradicted after the look-ahead window", func(t *testing.T) {
+		content := "1/2/20, 10:00 - a: m\n1/3/20, 10:00 - a: m\n13/3/20, 10:00 - a: m"
+
+		var lastErr error
+		for _, err := range ParseReader(strings.NewReader(content), &ParseStringOptions{LookAhead: 2}) {
+			lastErr = err
+		}
+		if !errors.Is(lastErr, ErrDateOrderChanged) {
+			t.Errorf("Expected ErrDateOrderChanged, got %v", lastErr)
+		}
+
+		lastErr = nil
+		for _, err := range ParseReader(strings.NewReader(content), &ParseStringOptions{LookAhead: -1}) {
+			lastErr = err
+		}
+		if lastErr != nil {
+			t.Errorf("Unexpected error with unlimited look-ahead: %v", lastErr)
+		}
+	})
+
+	t.Run("dateOrderTracker matches daysBeforeMonths", func(t *testing.T) {
+		samples := [][][]int{
+			{},
+			{{4, 6, 2017}},
+			{{4, 6, 2017}, {11, 10, 2017}},
+			{{13, 6, 2017}, {4, 13, 2017}},
+			{{4, 13, 2017}, {6, 15, 2017}},
+			{{8, 3, 2017}, {2, 4, 2017}},
+			{{3, 8, 2017}, {4, 2, 2017}},
+			{{1, 1, 2017}, {2, 1, 2017}, {1, 1, 2018}, {1, 2, 2018}},
+			{{1, 5, 2017}, {2, 7, 2017}, {3, 9, 2017}},
+		}
+
+		for _, dates := range samples {
+			var tracker dateOrderTracker
+			for _, date := range dates {
+				tracker.add(date)
+			}
+
+			expected, result := daysBeforeMonths(dates), tracker.result()
+			if (expected == nil) != (result == nil) || (expected != nil && *expected != *result) {
+				t.Errorf("Expected tracker result for %v to match daysBeforeMonths", dates)
+			}
+		}
+	})
+}
+
+// TestDiagnostics tests the problems reported while parsing
+func TestDiagnostics(t *testing.T) {
+	content := "orphan line\r\n" +
+		"\n" +
+		"13/06/2020, 10:00 - a: m\n" +
+		"31/02/2020, 10:00 - a: m\n" +
+		"13/06/2020, 25:00 - a: m\n" +
+		"13/06/2020, 0:30 PM - a: m"
+
+	expected := []Diagnostic{
+		{Kind: DiagnosticDroppedLine, Line: 1, Offset: 0},
+		{Kind: DiagnosticInvalidDate, Line: 4, Offset: 39},
+		{Kind: DiagnosticInvalidTime, Line: 5, Offset: 64},
+		{Kind: DiagnosticInvalidTime, Line: 6, Offset: 89},
+	}
+
+	t.Run("Parse", func(t *testing.T) {
+		result, err := Parse(content, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(result.Messages) != 4 {
+			t.Errorf("Expected 4 messages, got %d", len(result.Messages))
+		}
+		if len(result.Diagnostics) != len(expected) {
+			t.Fatalf("Expected %d diagnostics, got %v", len(expected), result.Diagnostics)
+		}
+		for i, d := range result.Diagnostics {
+			if d.Kind != expected[i].Kind || d.Line != expected[i].Line || d.Offset != expected[i].Offset {
+				t.Errorf("Expected diagnostic %+v, got %+v", expected[i], d)
+			}
+		}
+	})
+
+	t.Run("Scanner", func(t *testing.T) {
+		s := NewScanner(strings.NewReader(content), nil)
+		for s.Scan() {
+		}
+		result, _ := Parse(content, nil)
+		if !reflect.DeepEqual(s.Diagnostics(), result.Diagnostics) {
+			t.Errorf("Expected %v, got %v", result.Diagnostics, s.Diagnostics())
+		}
+	})
+
+	t.Run("Ambiguous date order", func(t *testing.T) {
+		result, err := Parse("1/2/20, 10:00 - a: m\n1/3/20, 10:00 - a: m", nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(result.Diagnostics) != 1 || result.Diagnostics[0].Kind != DiagnosticAmbiguousDateOrder {
+			t.Errorf("Expected an ambiguous date order diagnostic, got %v", result.Diagnostics)
+		}
+
+		result, _ = Parse("1/2/20, 10:00 - a: m", &ParseStringOptions{DaysFirst: &[]bool{false}[0]})
+		if len(result.Diagnostics) != 0 {
+			t.Errorf("Expected no diagnostics when DaysFirst is set, got %v", result.Diagnostics)
+		}
+	})
+}
+
+// TestStrict tests that strict parsing fails instead of guessing
+func TestStrict(t *testing.T) {
+	options := ParseStringOptions{Strict: true}
+
+	tests := []struct {
+		description string
+		content     string
+		kind        DiagnosticKind
+		line        int
+	}{
+		{"Orphan text", "orphan\n13/06/2020, 10:00 - a: m", DiagnosticDroppedLine, 1},
+		{"Invalid date", "13/06/2020, 10:00 - a: m\n31/02/2020, 10:00 - a: m", DiagnosticInvalidDate, 2},
+		{"Invalid time", "13/06/2020, 10:00 - a: m\n13/06/2020, 10:60 - a: m", DiagnosticInvalidTime, 2},
+		{"Undetected date order", "1/2/20, 10:00 - a: m", DiagnosticUndetectedDateOrder, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			_, err := ParseString(test.content, &options)
+
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("Expected a ParseError, got %v", err)
+			}
+			if parseErr.Kind != test.kind || parseErr.Line != test.line {
+				t.Errorf("Expected %q on line %d, got %q on line %d", test.kind, test.line, parseErr.Kind, parseErr.Line)
+			}
+
+			var lastErr error
+			for _, err := range ParseReader(strings.NewReader(test.content), &options) {
+				lastErr = err
+			}
+			if !errors.As(lastErr, &parseErr) || parseErr.Kind != test.kind {
+				t.Errorf("Expected the Scanner to fail with %q, got %v", test.kind, lastErr)
+			}
+		})
+	}
+
+	t.Run("Detected date order", func(t *testing.T) {
+		messages, err := ParseString("1/2/20, 10:00 - a: m\n1/3/20, 10:00 - a: m", &options)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if len(messages) != 2 {
+			t.Errorf("Expected 2 messages, got %d", len(messages))
+		}
+	})
+}
+
+// TestLocation tests interpreting dates in a time zone
+func TestLocation(t *testing.T) {
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	if err != nil {
+		t.Skipf("Time zone data not available: %v", err)
+	}
+
+	content := "30/03/2024, 12:00 - a: m\n" +
+		"31/03/2024, 02:30 - a: m\n" +
+		"27/10/2024, 02:30 - a: m"
+
+	result, err := Parse(content, &ParseStringOptions{Location: warsaw})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	messages := result.Messages
+
+	expected := []time.Time{
+		time.Date(2024, 3, 30, 11, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 31, 1, 30, 0, 0, time.UTC),
+		time.Date(2024, 10, 27, 0, 30, 0, 0, time.UTC),
+	}
+	for i, message := range messages {
+		if !message.Date.Equal(expected[i]) {
+			t.Errorf("Expected %v, got %v", expected[i], message.Date.UTC())
+		}
+		if message.Date.Location() != warsaw {
+			t.Errorf("Expected dates in %v, got %v", warsaw, message.Date.Location())
+		}
+	}
+
+	if messages[1].WallClock != time.Date(2024, 3, 31, 2, 30, 0, 0, time.UTC) {
+		t.Errorf("Expected the wall clock time to be kept, got %v", messages[1].WallClock)
+	}
+
+	kinds := []DiagnosticKind{DiagnosticNonexistentLocalTime, DiagnosticAmbiguousLocalTime}
+	var got []DiagnosticKind
+	for _, d := range result.Diagnostics {
+		got = append(got, d.Kind)
+	}
+	if !reflect.DeepEqual(got, kinds) {
+		t.Errorf("Expected diagnostics %v, got %v", kinds, got)
+	}
+}
+
+// TestConcurrency tests that building messages on several goroutines gives
+// the same messages and diagnostics, in the same order
+func TestConcurrency(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("13/06/2020, 09:00 - Messages are end-to-end encrypted.\n")
+	for i := 0; i < 50; i++ {
+		b.WriteString("13/06/2020, 10:00 - a: m\n" +
+			"continued\n" +
+			"31/02/2020, 10:00 - b: <Media omitted>\n" +
+			"13/06/2020, 25:00 - a left\n")
+	}
+	chats := map[string]string{"diagnostics": b.String()}
+	for _, example := range chatExamples {
+		content, err := os.ReadFile(example.filePath)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		chats[example.filePath] = string(content)
+	}
+
+	for name, content := range chats {
+		t.Run(name, func(t *testing.T) {
+			expected, err := Parse(content, &ParseStringOptions{ParseAttachments: true})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, concurrency := range []int{2, 7, -1, 1000} {
+				result, err := Parse(content, &ParseStringOptions{ParseAttachments: true, Concurrency: concurrency})
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if !reflect.DeepEqual(result, expected) {
+					t.Errorf("Concurrency %d: expected %+v, got %+v", concurrency, expected, result)
+				}
+			}
+		})
+	}
+
+	t.Run("Strict", func(t *testing.T) {
+		_, err := ParseString(chats["diagnostics"], &ParseStringOptions{Strict: true, Concurrency: 4})
+
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Fatalf("Expected a ParseError, got %v", err)
+		}
+		if parseErr.Kind != DiagnosticInvalidDate || parseErr.Line != 4 {
+			t.Errorf("Expected the first invalid date on line 4, got %q on line %d", parseErr.Kind, parseErr.Line)
+		}
+	})
+}
+
 type chatTestExample struct {
 	description   string
 	filePath      string
@@ -0,0 +1,124 @@
+package parser
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// regexPollOption matches an option of a poll, like
+	// "OPTION: Pizza (3 votes)"
+	regexPollOption = regexp.MustCompile(`^OPTION: (.*) \((\d+) votes?\)$`)
+	// regexLocation matches a shared location, like
+	// "location: https://maps.google.com/?q=52.2297,21.0122"
+	regexLocation = regexp.MustCompile(`(?i)^(live )?location: (https?://\S*?[?&](?:q|ll|query)=(-?\d+(?:\.\d+)?)(?:,|%2C)\s*(-?\d+(?:\.\d+)?)\S*)$`)
+	// regexLiveLocation matches the notice of a live location without
+	// coordinates
+	regexLiveLocation = regexp.MustCompile(`(?i)^live location shared$`)
+	// regexContactFileName matches the number iOS puts before the names of
+	// exported files
+	regexContactFileName = regexp.MustCompile(`^\d+-`)
+)
+
+// parsePayload parses the structured content of a message: a poll, a shared
+// location or a contact card attachment
+func parsePayload(message string, attachment *Attachment) *Payload {
+	lines := strings.Split(message, "\n")
+	for i := range lines {
+		lines[i] = trimMarks(lines[i])
+	}
+
+	if lines[0] == "POLL:" {
+		if poll := parsePoll(lines[1:]); poll != nil {
+			return &Payload{Kind: PayloadPoll, Poll: poll}
+		}
+	}
+
+	if len(lines) == 1 {
+		if matches := regexLocation.FindStringSubmatch(lines[0]); matches != nil {
+			latitude, _ := strconv.ParseFloat(matches[3], 64)
+			longitude, _ := strconv.ParseFloat(matches[4], 64)
+			kind := PayloadLocation
+			if matches[1] != "" {
+				kind = PayloadLiveLocation
+			}
+			return &Payload{Kind: kind, Location: &Location{Latitude: latitude, Longitude: longitude, URL: matches[2]}}
+		}
+		if regexLiveLocation.MatchString(lines[0]) {
+			return &Payload{Kind: PayloadLiveLocation}
+		}
+	}
+
+	if attachment != nil && attachment.Kind == AttachmentContactCard && !attachment.Omitted {
+		name := strings.TrimSuffix(attachment.FileName, "."+attachment.Extension)
+		name = regexContactFileName.ReplaceAllString(name, "")
+		return &Payload{Kind: PayloadContactCard, ContactCard: &ContactCard{Name: name}}
+	}
+
+	return nil
+}
+
+// parsePoll parses the question and options following "POLL:"
+func parsePoll(lines []string) *Poll {
+	if len(lines) < 2 {
+		return nil
+	}
+
+	poll := &Poll{Question: lines[0]}
+	for _, line := range lines[1:] {
+		matches := regexPollOption.FindStringSubmatch(line)
+		if matches == nil {
+			return nil
+		}
+		votes, _ := strconv.Atoi(matches[2])
+		poll.Options = append(poll.Options, PollOption{Text: matches[1], Votes: votes})
+	}
+	return poll
+}
+
+// readVCard fills in a contact card from a vCard file, taking the formatted
+// name and every phone number
+func readVCard(r io.Reader, card *ContactCard) error {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		// Long values are folded over lines starting with a space or tab
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	for _, line := range lines {
+		property, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		// Drop the parameters, as in "TEL;type=CELL", and the group, as in
+		// "item1.TEL"
+		property, _, _ = strings.Cut(property, ";")
+		if i := strings.LastIndex(property, "."); i >= 0 {
+			property = property[i+1:]
+		}
+
+		switch strings.ToUpper(property) {
+		case "FN":
+			if value != "" {
+				card.Name = value
+			}
+		case "TEL":
+			if value != "" {
+				card.Phones = append(card.Phones, value)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestPayloads tests parsing polls, locations and contact cards
+func TestPayloads(t *testing.T) {
+	content := "13/06/2018, 21:25 - Anna: POLL:\n" +
+		"Where do we eat?\n" +
+		"OPTION: Pizza (3 votes)\n" +
+		"OPTION: Sushi (1 vote)\n" +
+		"13/06/2018, 21:26 - Bob: location: https://maps.google.com/?q=52.2297,21.0122\n" +
+		"\u200E[13/06/2018, 21:27:00] Carl: \u200ELive location shared\n" +
+		"\u200E[13/06/2018, 21:28:00] Bob: \u200E<attached: 00000016-John Doe.vcf>\n" +
+		"13/06/2018, 21:29 - Anna: POLL: is not a poll\n" +
+		"13/06/2018, 21:30 - Anna: location: nowhere"
+
+	messages, err := ParseString(content, &ParseStringOptions{ParseAttachments: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expect := []*Payload{
+		{Kind: PayloadPoll, Poll: &Poll{Question: "Where do we eat?", Options: []PollOption{{"Pizza", 3}, {"Sushi", 1}}}},
+		{Kind: PayloadLocation, Location: &Location{Latitude: 52.2297, Longitude: 21.0122, URL: "https://maps.google.com/?q=52.2297,21.0122"}},
+		{Kind: PayloadLiveLocation},
+		{Kind: PayloadContactCard, ContactCard: &ContactCard{Name: "John Doe"}},
+		nil,
+		nil,
+	}
+	if len(messages) != len(expect) {
+		t.Fatalf("Expected %d messages, got %d", len(expect), len(messages))
+	}
+	for i, payload := range expect {
+		if !reflect.DeepEqual(messages[i].Payload, payload) {
+			t.Errorf("Message %d: expected payload %+v, got %+v", i, payload, messages[i].Payload)
+		}
+	}
+}
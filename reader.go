@@ -0,0 +1,279 @@
+package parser
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"iter"
+	"strings"
+)
+
+// defaultLookAhead is the number of messages held back by a Scanner while the
+// date format is still undecided, when ParseStringOptions.LookAhead is zero
+const defaultLookAhead = 1000
+
+// ErrDateOrderChanged is reported by a Scanner when the day/month order it
+// picked after its look-ahead window differs from the order ParseString
+// would detect from the whole chat, and by ParseIncremental when a tail
+// contradicts the order guessed from the previous ones. Messages already
+// returned carry dates parsed with the wrong order; parse again with
+// LookAhead set to -1 or with DaysFirst set.
+var ErrDateOrderChanged = errors.New("parser: date order detected from the look-ahead window is contradicted by later messages")
+
+// sourceLine is a line of the chat log with its position
+type sourceLine struct {
+	text   string
+	number int   // 1-based
+	offset int64 // byte offset of the first character
+}
+
+// lineReader splits a stream into lines on \r\n, \r or \n, keeping the empty
+// line after a final line break
+type lineReader struct {
+	r      *bufio.Reader
+	lines  []sourceLine
+	number int
+	offset int64
+	eof    bool
+}
+
+func newLineReader(r io.Reader) *lineReader {
+	return &lineReader{r: bufio.NewReader(r)}
+}
+
+// next returns the next line, or io.EOF once the input is exhausted
+func (lr *lineReader) next() (sourceLine, error) {
+	for len(lr.lines) == 0 {
+		if lr.eof {
+			return sourceLine{}, io.EOF
+		}
+
+		chunk, err := lr.r.ReadString('\n')
+		end := lr.offset + int64(len(chunk))
+		switch {
+		case err == io.EOF:
+			// The last line has no terminator, but is a line even when empty
+			lr.eof = true
+		case err != nil:
+			return sourceLine{}, err
+		default:
+			chunk = strings.TrimSuffix(chunk[:len(chunk)-1], "\r")
+		}
+
+		for _, text := range strings.Split(chunk, "\r") {
+			lr.number++
+			lr.lines = append(lr.lines, sourceLine{text: text, number: lr.number, offset: lr.offset})
+			lr.offset += int64(len(text)) + 1
+		}
+		lr.offset = end
+	}
+
+	line := lr.lines[0]
+	lr.lines = lr.lines[1:]
+	return line, nil
+}
+
+// pendingMessage is a matched raw message waiting for the date order decision
+type pendingMessage struct {
+	raw    RawMessage
+	header messageHeader
+}
+
+// Scanner reads messages from a WhatsApp chat log one at a time, with memory
+// bounded by the longest message and the look-ahead window.
+//
+// When DaysFirst is not set, messages are held back until the day/month
+// order is known: as soon as a day above 12 proves it, or when LookAhead
+// messages have been read, or at the end of the input. A decision taken from
+// the window alone is checked against the rest of the chat, and
+// ErrDateOrderChanged is reported if ParseString would have decided
+// differently.
+type Scanner struct {
+	lines     *lineReader
+	assembler messageAssembler
+	ctx       *parseContext
+	tracker   dateOrderTracker
+	daysFirst *bool
+	guessed   bool // daysFirst was decided before every date was seen
+	pending   []pendingMessage
+	ready     []Message
+	emitted   int
+	message   Message
+	err       error
+	done      bool
+}
+
+// NewScanner returns a Scanner reading a chat log from r
+func NewScanner(r io.Reader, options *ParseStringOptions) *Scanner {
+	s := &Scanner{lines: newLineReader(r)}
+
+	ctx, err := newParseContext(options)
+	if err != nil {
+		s.err = err
+		return s
+	}
+	s.ctx = ctx
+	s.assembler.ctx = ctx
+
+	if ctx.options.DaysFirst != nil {
+		daysFirst := *ctx.options.DaysFirst
+		s.daysFirst = &daysFirst
+	}
+	return s
+}
+
+// Scan advances to the next message, which is then available through
+// Message. It returns false at the end of the input or on error.
+func (s *Scanner) Scan() bool {
+	for len(s.ready) == 0 {
+		if s.done || s.err != nil {
+			return false
+		}
+		s.advance()
+	}
+
+	s.message = s.ready[0]
+	s.ready = s.ready[1:]
+	return true
+}
+
+// Message returns the message read by the last call to Scan
+func (s *Scanner) Message() Message {
+	return s.message
+}
+
+// Err returns the first error encountered by the Scanner
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+// Diagnostics returns the problems found so far
+func (s *Scanner) Diagnostics() []Diagnostic {
+	if s.ctx == nil {
+		return nil
+	}
+	return s.ctx.diagnostics
+}
+
+// advance reads one line and queues the messages it completes
+func (s *Scanner) advance() {
+	line, err := s.lines.next()
+	if err == io.EOF {
+		if rawMsg, ok := s.assembler.flush(); ok {
+			s.accept(rawMsg)
+		}
+		s.finish()
+		s.done = true
+		return
+	}
+	if err != nil {
+		s.err = err
+		return
+	}
+
+	if rawMsg, ok := s.assembler.push(line); ok {
+		s.accept(rawMsg)
+	}
+	if s.err == nil && s.ctx.err != nil {
+		s.err = s.ctx.err
+	}
+}
+
+// accept records the date of a complete raw message and either converts it
+// or holds it back until the date order is decided
+func (s *Scanner) accept(rawMsg RawMessage) {
+	header, ok := s.ctx.grammar.match(rawMsg)
+	if !ok {
+		return
+	}
+	s.tracker.add(dateComponents(header))
+
+	if s.daysFirst != nil {
+		if s.guessed && !*s.daysFirst && s.tracker.FirstAbove12 {
+			s.err = ErrDateOrderChanged
+			return
+		}
+		s.emit(rawMsg, header)
+		return
+	}
+
+	s.pending = append(s.pending, pendingMessage{raw: rawMsg, header: header})
+
+	lookAhead := s.ctx.options.LookAhead
+	if lookAhead == 0 {
+		lookAhead = defaultLookAhead
+	}
+
+	// A day above 12 settles the order for the whole chat
+	if s.tracker.FirstAbove12 {
+		s.decide(true)
+	} else if lookAhead > 0 && len(s.pending) >= lookAhead && (!s.ctx.options.Strict || s.tracker.result() != nil) {
+		// In strict mode the window grows until the order can be detected
+		s.guessed = true
+		s.decideFromTracker()
+	}
+}
+
+// decideFromTracker decides the date order from the dates seen so far
+func (s *Scanner) decideFromTracker() {
+	detected := s.tracker.result()
+	daysFirst := resolveDaysFirst(s.ctx.options, detected)
+	if !s.tracker.FirstAbove12 && !s.tracker.SecondAbove12 {
+		s.ctx.reportAmbiguousDateOrder(daysFirst, detected != nil)
+	}
+	s.decide(daysFirst)
+}
+
+// finish decides the date order if still needed and checks a guessed one
+// against every date in the chat
+func (s *Scanner) finish() {
+	if s.daysFirst == nil {
+		if len(s.pending) > 0 {
+			s.decideFromTracker()
+		}
+		return
+	}
+	if s.err == nil && s.guessed && resolveDaysFirst(s.ctx.options, s.tracker.result()) != *s.daysFirst {
+		s.err = ErrDateOrderChanged
+	}
+}
+
+// decide fixes the date order and releases the held back messages
+func (s *Scanner) decide(daysFirst bool) {
+	s.daysFirst = &daysFirst
+	for _, p := range s.pending {
+		if s.err != nil {
+			break
+		}
+		s.emit(p.raw, p.header)
+	}
+	s.pending = nil
+}
+
+// emit converts a raw message and queues it for Scan
+func (s *Scanner) emit(rawMsg RawMessage, header messageHeader) {
+	message := s.ctx.buildMessage(rawMsg, header, *s.daysFirst)
+	if s.ctx.err != nil {
+		s.err = s.ctx.err
+		return
+	}
+	markEncryptionNotice(s.emitted, &message)
+	s.emitted++
+	s.ready = append(s.ready, message)
+}
+
+// ParseReader parses a WhatsApp chat log read from r, yielding each message
+// as soon as it is complete. A non-nil error is yielded at most once, last.
+func ParseReader(r io.Reader, options *ParseStringOptions) iter.Seq2[Message, error] {
+	return func(yield func(Message, error) bool) {
+		s := NewScanner(r, options)
+		for s.Scan() {
+			if !yield(s.Message(), nil) {
+				return
+			}
+		}
+		if err := s.Err(); err != nil {
+			yield(Message{}, err)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package parser
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// DefaultIdleGap is the silence after which a message starts a new
+// conversation, when AnalyzeResponses is given no gap
+const DefaultIdleGap = 6 * time.Hour
+
+// ResponseStats are the reply latencies of one author to another
+type ResponseStats struct {
+	Author    string          `json:"author"`    // who replied
+	RepliedTo string          `json:"repliedTo"` // whose message was answered
+	Count     int             `json:"count"`
+	Median    time.Duration   `json:"median"`
+	P90       time.Duration   `json:"p90"`
+	Latencies []time.Duration `json:"-"` // sorted
+}
+
+// Percentile returns the latency below which the given percentage of the
+// replies fall, from 0 to 100, interpolating between replies
+func (r *ResponseStats) Percentile(p float64) time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+
+	rank := p / 100 * float64(len(r.Latencies)-1)
+	rank = math.Max(0, math.Min(rank, float64(len(r.Latencies)-1)))
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	weight := rank - float64(lower)
+	return r.Latencies[lower] + time.Duration(weight*float64(r.Latencies[upper]-r.Latencies[lower]))
+}
+
+// ResponseAnalysis tells who replies to whom and how fast, and who starts the
+// conversations
+type ResponseAnalysis struct {
+	IdleGap   time.Duration   `json:"idleGap"`
+	Responses []ResponseStats `json:"responses"` // by author, then replied to author
+	// Initiations counts by author the messages starting a conversation: the
+	// first message and those sent after a silence longer than IdleGap
+	Initiations map[string]int `json:"initiations"`
+}
+
+// AnalyzeResponses splits the messages into sessions with Sessionize and
+// measures a reply whenever the author changes within a session, from the
+// last message of the previous author. System messages are left out. A gap
+// of zero or less means DefaultIdleGap.
+func AnalyzeResponses(messages []Message, idleGap time.Duration) *ResponseAnalysis {
+	if idleGap <= 0 {
+		idleGap = DefaultIdleGap
+	}
+	analysis := &ResponseAnalysis{IdleGap: idleGap, Initiations: make(map[string]int)}
+
+	type pair struct{ author, repliedTo string }
+	latencies := make(map[pair][]time.Duration)
+
+	for _, session := range Sessionize(messages, idleGap) {
+		first := &messages[session.Messages[0]]
+		analysis.Initiations[*first.Author]++
+
+		previous := first
+		for _, i := range session.Messages[1:] {
+			message := &messages[i]
+			if *message.Author != *previous.Author {
+				// Messages out of order reply at once
+				latency := message.Date.Sub(previous.Date)
+				if latency < 0 {
+					latency = 0
+				}
+				key := pair{*message.Author, *previous.Author}
+				latencies[key] = append(latencies[key], latency)
+			}
+			previous = message
+		}
+	}
+
+	for key, durations := range latencies {
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+		stats := ResponseStats{Author: key.author, RepliedTo: key.repliedTo, Count: len(durations), Latencies: durations}
+		stats.Median = stats.Percentile(50)
+		stats.P90 = stats.Percentile(90)
+		analysis.Responses = append(analysis.Responses, stats)
+	}
+	sort.Slice(analysis.Responses, func(i, j int) bool {
+		a, b := analysis.Responses[i], analysis.Responses[j]
+		if a.Author != b.Author {
+			return a.Author < b.Author
+		}
+		return a.RepliedTo < b.RepliedTo
+	})
+
+	return analysis
+}
@@ -0,0 +1,53 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+// TestAnalyzeResponses tests the reply latencies and conversation starts
+func TestAnalyzeResponses(t *testing.T) {
+	content := "11/06/2018, 09:00 - Anna created group \"Trip\"\n" +
+		"11/06/2018, 09:01 - Anna: Where do we go?\n" +
+		"11/06/2018, 09:02 - Anna: Anyone?\n" +
+		"11/06/2018, 09:04 - Bob: The beach\n" +
+		"11/06/2018, 09:05 - Anna added Carl\n" +
+		"11/06/2018, 09:14 - Anna: Great\n" +
+		"11/06/2018, 18:00 - Bob: Packed?\n" +
+		"11/06/2018, 18:30 - Anna: Yes\n" +
+		"11/06/2018, 18:31 - Bob: Good"
+
+	messages, err := ParseString(content, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	analysis := AnalyzeResponses(messages, 0)
+	if analysis.IdleGap != DefaultIdleGap {
+		t.Errorf("Expected the default idle gap, got %v", analysis.IdleGap)
+	}
+	if analysis.Initiations["Anna"] != 1 || analysis.Initiations["Bob"] != 1 {
+		t.Errorf("Expected one conversation started by each, got %v", analysis.Initiations)
+	}
+
+	if len(analysis.Responses) != 2 {
+		t.Fatalf("Expected 2 author pairs, got %+v", analysis.Responses)
+	}
+	anna, bob := analysis.Responses[0], analysis.Responses[1]
+	if anna.Author != "Anna" || anna.RepliedTo != "Bob" || anna.Count != 2 || anna.Median != 20*time.Minute {
+		t.Errorf("Unexpected replies of Anna %+v", anna)
+	}
+	if bob.Author != "Bob" || bob.RepliedTo != "Anna" || bob.Count != 2 || bob.Median != 90*time.Second || bob.P90 != 114*time.Second {
+		t.Errorf("Unexpected replies of Bob %+v", bob)
+	}
+	if bob.Percentile(0) != 1*time.Minute || bob.Percentile(100) != 2*time.Minute {
+		t.Errorf("Unexpected percentiles %v %v", bob.Percentile(0), bob.Percentile(100))
+	}
+
+	t.Run("Idle gap", func(t *testing.T) {
+		analysis := AnalyzeResponses(messages, 5*time.Minute)
+		if analysis.Initiations["Anna"] != 3 || analysis.Initiations["Bob"] != 1 {
+			t.Errorf("Expected more conversations with a shorter gap, got %v", analysis.Initiations)
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package parser
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	// minAdaptiveGap and maxAdaptiveGap bound the gap chosen by AdaptiveGap
+	minAdaptiveGap = 10 * time.Minute
+	maxAdaptiveGap = 24 * time.Hour
+)
+
+// Session is a run of messages without a silence longer than the gap given
+// to Sessionize
+type Session struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// Participants are the authors of the session, in the order they first
+	// wrote
+	Participants []string `json:"participants"`
+	// Messages are the indices of the messages of the session in the slice
+	// given to Sessionize
+	Messages []int `json:"messages"`
+}
+
+// DailyCount is a number of sessions started on a day
+type DailyCount struct {
+	Day      time.Time `json:"day"`
+	Sessions int       `json:"sessions"`
+}
+
+// AdaptiveGap derives a session gap from the chat's own rhythm: the silences
+// between consecutive messages far above the usual ones, the third quartile
+// plus three times the interquartile range, kept between 10 minutes and a
+// day. System messages are left out.
+func AdaptiveGap(messages []Message) time.Duration {
+	var gaps []time.Duration
+	var previous *Message
+	for i := range messages {
+		message := &messages[i]
+		if message.IsSystem || message.Author == nil {
+			continue
+		}
+		if previous != nil && message.Date.After(previous.Date) {
+			gaps = append(gaps, message.Date.Sub(previous.Date))
+		}
+		previous = message
+	}
+	if len(gaps) == 0 {
+		return maxAdaptiveGap
+	}
+
+	sort.Slice(gaps, func(i, j int) bool { return gaps[i] < gaps[j] })
+	quartiles := ResponseStats{Latencies: gaps}
+	q1, q3 := quartiles.Percentile(25), quartiles.Percentile(75)
+	gap := q3 + 3*(q3-q1)
+	if gap < minAdaptiveGap {
+		return minAdaptiveGap
+	}
+	if gap > maxAdaptiveGap {
+		return maxAdaptiveGap
+	}
+	return gap
+}
+
+// Sessionize splits a chat into sessions separated by silences longer than
+// gap, or than AdaptiveGap when gap is zero or less. Messages are taken in
+// order, and system messages are left out.
+func Sessionize(messages []Message, gap time.Duration) []Session {
+	if gap <= 0 {
+		gap = AdaptiveGap(messages)
+	}
+
+	var sessions []Session
+	var current *Session
+	seen := make(map[string]bool)
+	for i, message := range messages {
+		if message.IsSystem || message.Author == nil {
+			continue
+		}
+
+		if current == nil || message.Date.Sub(current.End) > gap {
+			sessions = append(sessions, Session{Start: message.Date})
+			current = &sessions[len(sessions)-1]
+			clear(seen)
+		}
+
+		if message.Date.After(current.End) {
+			current.End = message.Date
+		}
+		current.Messages = append(current.Messages, i)
+		if !seen[*message.Author] {
+			seen[*message.Author] = true
+			current.Participants = append(current.Participants, *message.Author)
+		}
+	}
+
+	return sessions
+}
+
+// SessionsPerDay counts the sessions started on each day, in the time zone of
+// their start, from the day of the first session to that of the last
+func SessionsPerDay(sessions []Session) []DailyCount {
+	if len(sessions) == 0 {
+		return nil
+	}
+
+	location := sessions[0].Start.Location()
+	var counts []DailyCount
+	index := make(map[int64]int)
+	last := sessions[len(sessions)-1].Start
+	for day := periodStart(sessions[0].Start, PeriodDay); !day.After(last); day = nextPeriod(day, PeriodDay) {
+		index[day.Unix()] = len(counts)
+		counts = append(counts, DailyCount{Day: day})
+	}
+
+	for _, session := range sessions {
+		if i, ok := index[periodStart(session.Start.In(location), PeriodDay).Unix()]; ok {
+			counts[i].Sessions++
+		}
+	}
+	return counts
+}
@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestSessionize tests the splitting of chats into sessions
+func TestSessionize(t *testing.T) {
+	content := "11/06/2018, 09:00 - Anna created group \"Trip\"\n" +
+		"11/06/2018, 09:01 - Anna: Where do we go?\n" +
+		"11/06/2018, 09:04 - Bob: The beach\n" +
+		"11/06/2018, 09:05 - Anna added Carl\n" +
+		"11/06/2018, 09:14 - Anna: Great\n" +
+		"11/06/2018, 18:00 - Bob: Packed?\n" +
+		"11/06/2018, 18:30 - Carl: Yes\n" +
+		"11/06/2018, 18:31 - Bob: Good\n" +
+		"13/06/2018, 07:00 - Carl: Leaving"
+
+	messages, err := ParseString(content, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("fixed gap", func(t *testing.T) {
+		sessions := Sessionize(messages, time.Hour)
+		if len(sessions) != 3 {
+			t.Fatalf("Expected 3 sessions, got %+v", sessions)
+		}
+
+		first := sessions[0]
+		if !reflect.DeepEqual(first.Messages, []int{1, 2, 4}) {
+			t.Errorf("Expected the user messages of the morning, got %v", first.Messages)
+		}
+		if !reflect.DeepEqual(first.Participants, []string{"Anna", "Bob"}) {
+			t.Errorf("Expected Anna and Bob, got %v", first.Participants)
+		}
+		if first.Start.Hour() != 9 || first.Start.Minute() != 1 || first.End.Minute() != 14 {
+			t.Errorf("Unexpected bounds %v - %v", first.Start, first.End)
+		}
+
+		if !reflect.DeepEqual(sessions[1].Participants, []string{"Bob", "Carl"}) {
+			t.Errorf("Expected Bob and Carl, got %v", sessions[1].Participants)
+		}
+
+		if sessions := Sessionize(messages, 30*time.Minute); len(sessions) != 3 {
+			t.Errorf("Expected a gap of exactly 30 minutes to keep a session, got %d sessions", len(sessions))
+		}
+		if sessions := Sessionize(messages, 29*time.Minute); len(sessions) != 4 {
+			t.Errorf("Expected 4 sessions, got %d", len(sessions))
+		}
+	})
+
+	t.Run("adaptive gap", func(t *testing.T) {
+		gap := AdaptiveGap(messages)
+		if gap < minAdaptiveGap || gap > maxAdaptiveGap {
+			t.Errorf("Expected a gap within bounds, got %v", gap)
+		}
+		if sessions := Sessionize(messages, 0); len(sessions) != len(Sessionize(messages, gap)) {
+			t.Errorf("Expected the adaptive gap to be used, got %d sessions", len(sessions))
+		}
+
+		// Replies within seconds make every pause of minutes a new session
+		var quick []Message
+		start := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+		for i, offset := range []time.Duration{0, 5, 10, 15, 20, 25, 30, 35, 3600, 3605} {
+			author := []string{"Anna", "Bob"}[i%2]
+			quick = append(quick, Message{Date: start.Add(offset * time.Second), Author: &author})
+		}
+		if gap := AdaptiveGap(quick); gap != minAdaptiveGap {
+			t.Errorf("Expected the minimum gap, got %v", gap)
+		}
+		if sessions := Sessionize(quick, 0); len(sessions) != 2 {
+			t.Errorf("Expected 2 sessions, got %d", len(sessions))
+		}
+
+		if gap := AdaptiveGap(nil); gap != maxAdaptiveGap {
+			t.Errorf("Expected the maximum gap without messages, got %v", gap)
+		}
+	})
+
+	t.Run("sessions per day", func(t *testing.T) {
+		counts := SessionsPerDay(Sessionize(messages, time.Hour))
+		if len(counts) != 3 {
+			t.Fatalf("Expected 3 days, got %+v", counts)
+		}
+		for i, expected := range []int{2, 0, 1} {
+			if counts[i].Sessions != expected {
+				t.Errorf("Expected %d sessions on day %d, got %d", expected, i, counts[i].Sessions)
+			}
+		}
+		if counts[1].Day.Day() != 12 {
+			t.Errorf("Expected the 12th, got %v", counts[1].Day)
+		}
+
+		if counts := SessionsPerDay(nil); counts != nil {
+			t.Errorf("Expected no days, got %+v", counts)
+		}
+	})
+}
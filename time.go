@@ -1,13 +1,18 @@
 package parser
 
 import (
+	"fmt"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // ==================== Time Functions ====================
 
+var regexNotAMPM = regexp.MustCompile(`[^apmAPM]`)
+
 // convertTime12to24 converts time from 12-hour format to 24-hour format
 func convertTime12to24(timeStr string, ampm string) string {
 	parts := regexSplitTime.Split(timeStr, -1)
@@ -52,8 +57,82 @@ func normalizeTime(timeStr string) string {
 	return hours + ":" + minutes + ":" + seconds
 }
 
-// normalizeAMPM normalizes AM/PM indicators to uppercase without other characters
+// normalizeAMPM normalizes the AM/PM markers of registered locales to "AM" or
+// "PM", and other indicators to uppercase without other characters
 func normalizeAMPM(ampm string) string {
-	ampm = regexp.MustCompile(`[^apmAPM]`).ReplaceAllString(ampm, "")
+	if grammar, err := grammarFor(""); err == nil {
+		if meaning := grammar.meaning(ampm); meaning != "" {
+			return meaning
+		}
+	}
+
+	ampm = regexNotAMPM.ReplaceAllString(ampm, "")
 	return strings.ToUpper(ampm)
 }
+
+// isValidTime checks the hour, minute and second of a normalized time, and
+// that a 12-hour time was written with an hour between 1 and 12
+func isValidTime(timeStr string, twelveHour bool, hour, minute, second int) bool {
+	if twelveHour {
+		written, err := strconv.Atoi(regexSplitTime.Split(timeStr, -1)[0])
+		if err != nil || written < 1 || written > 12 {
+			return false
+		}
+	}
+	return hour >= 0 && hour < 24 && minute >= 0 && minute < 60 && second >= 0 && second < 60
+}
+
+// localInstants returns the instants at which clocks in loc show the given
+// wall clock time: none in a gap when clocks go forward, and two in the hour
+// repeated when they go back
+func localInstants(wall time.Time, loc *time.Location) []time.Time {
+	naive := wall.Unix()
+
+	var result []time.Time
+	for _, probe := range []int64{naive - 86400, naive, naive + 86400} {
+		_, offset := time.Unix(probe, 0).In(loc).Zone()
+		candidate := time.Unix(naive-int64(offset), 0).In(loc)
+
+		y, mo, d := candidate.Date()
+		h, mi, s := candidate.Clock()
+		if time.Date(y, mo, d, h, mi, s, 0, time.UTC).Unix() != naive {
+			continue
+		}
+		if !slices.ContainsFunc(result, candidate.Equal) {
+			result = append(result, candidate)
+		}
+	}
+
+	slices.SortFunc(result, func(a, b time.Time) int { return a.Compare(b) })
+	return result
+}
+
+// localDate interprets a wall clock time in the Location option, reporting
+// times made ambiguous or skipped by DST transitions
+func (ctx *parseContext) localDate(rawMsg RawMessage, wall time.Time) time.Time {
+	loc := ctx.options.Location
+	instants := localInstants(wall, loc)
+
+	switch len(instants) {
+	case 1:
+		return instants[0]
+	case 0:
+		ctx.report(Diagnostic{
+			Kind:    DiagnosticNonexistentLocalTime,
+			Line:    rawMsg.Line,
+			Offset:  rawMsg.Offset,
+			Message: fmt.Sprintf("%s does not exist in %s", wall.Format(time.DateTime), loc),
+		})
+		// Use the offset from before the gap, which moves the time forward
+		_, offset := time.Unix(wall.Unix()-86400, 0).In(loc).Zone()
+		return time.Unix(wall.Unix()-int64(offset), 0).In(loc)
+	default:
+		ctx.report(Diagnostic{
+			Kind:    DiagnosticAmbiguousLocalTime,
+			Line:    rawMsg.Line,
+			Offset:  rawMsg.Offset,
+			Message: fmt.Sprintf("%s happens twice in %s", wall.Format(time.DateTime), loc),
+		})
+		return instants[0]
+	}
+}
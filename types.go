@@ -1,25 +1,235 @@
 package parser
 
-import "time"
+import (
+	"io"
+	"time"
+)
 
 type Message struct {
-	Date       time.Time   `json:"date"`
-	Author     *string     `json:"author"` // nil for system messages
-	IsSystem   bool        `json:"isSystem"`
-	Message    string      `json:"message"`
-	Attachment *Attachment `json:"attachment,omitempty"`
+	Date       time.Time    `json:"date"`
+	Author     *string      `json:"author"` // nil for system messages
+	IsSystem   bool         `json:"isSystem"`
+	Message    string       `json:"message"`
+	Attachment *Attachment  `json:"attachment,omitempty"`
+	Event      *SystemEvent `json:"event,omitempty"` // set for recognised system messages
+	// WallClock is the date and time as written in the chat, in UTC
+	// whatever the Location option, and unaffected by DST transitions
+	WallClock time.Time `json:"wallClock"`
+	// Deleted is set for the placeholders of deleted messages, which have an
+	// empty Message, and Edited for messages ending with the edit marker,
+	// which is stripped
+	Deleted bool `json:"deleted"`
+	Edited  bool `json:"edited"`
+	// FromOwner is set when the export tells that the owner of the exporting
+	// phone wrote the message, as for "You deleted this message"
+	FromOwner bool `json:"fromOwner"`
+	// Payload is the structured content of polls, shared locations and
+	// contact cards
+	Payload *Payload `json:"payload,omitempty"`
 }
 
 type Attachment struct {
-	FileName string `json:"fileName"`
+	FileName  string         `json:"fileName"`
+	Kind      AttachmentKind `json:"kind"`
+	Extension string         `json:"extension,omitempty"` // lower case, without the dot
+	MIMEType  string         `json:"mimeType,omitempty"`
+	// Caption is the text sent along with the file, the message without the
+	// attachment marker
+	Caption string `json:"caption,omitempty"`
+	Pages   int    `json:"pages,omitempty"` // for documents, when reported
+	// Omitted is set for the placeholders of exports made without media,
+	// like "<Media omitted>", which have no file name
+	Omitted bool `json:"omitted,omitempty"`
+	// Path and Size describe the archive entry holding the file, when the
+	// chat was parsed from an export archive with ParseZip
+	Path string `json:"path,omitempty"`
+	Size int64  `json:"size,omitempty"`
+
+	open func() (io.ReadCloser, error)
 }
 
 type RawMessage struct {
-	System bool
-	Msg    string
+	System bool   `json:"system"`
+	Msg    string `json:"msg"`
+	Line   int    `json:"line"`   // 1-based line number of the header
+	Offset int64  `json:"offset"` // byte offset of the header
+	// header is the header read from the first line, when the lines after
+	// it cannot change it
+	header *messageHeader
 }
 
 type ParseStringOptions struct {
 	DaysFirst        *bool `json:"daysFirst"`
 	ParseAttachments bool  `json:"parseAttachments"`
+	// LookAhead is the number of messages a Scanner buffers to detect the
+	// date format when DaysFirst is nil: 0 means 1000, negative means the
+	// whole chat. ParseString always uses the whole chat.
+	LookAhead int `json:"lookAhead"`
+	// Locale forces the AM/PM markers and system messages of one registered
+	// locale; by default those of every registered locale are accepted
+	Locale string `json:"locale"`
+	// Strict makes parsing fail with a *ParseError instead of guessing: on
+	// text before the first message, invalid dates and times, and when the
+	// date order cannot be detected
+	Strict bool `json:"strict"`
+	// Location is the time zone of the phone the chat was exported from.
+	// Dates are in UTC when it is nil.
+	Location *time.Location `json:"-"`
+	// Concurrency is the number of goroutines Parse and ParseString build
+	// messages on once the date order is decided: 0 or 1 builds them on the
+	// calling goroutine, a negative number uses GOMAXPROCS. Messages and
+	// diagnostics come in the same order either way.
+	Concurrency int `json:"concurrency"`
+}
+
+type AttachmentKind string
+
+const (
+	AttachmentImage       AttachmentKind = "image"
+	AttachmentVideo       AttachmentKind = "video"
+	AttachmentAudio       AttachmentKind = "audio"
+	AttachmentVoiceNote   AttachmentKind = "voiceNote"
+	AttachmentSticker     AttachmentKind = "sticker"
+	AttachmentGIF         AttachmentKind = "gif"
+	AttachmentDocument    AttachmentKind = "document"
+	AttachmentContactCard AttachmentKind = "contactCard"
+	AttachmentUnknown     AttachmentKind = "unknown"
+)
+
+type PayloadKind string
+
+const (
+	PayloadPoll         PayloadKind = "poll"
+	PayloadLocation     PayloadKind = "location"
+	PayloadLiveLocation PayloadKind = "liveLocation"
+	PayloadContactCard  PayloadKind = "contactCard"
+)
+
+// Payload holds the structured content of a message, in the field matching
+// its Kind. Live locations have a Location only when the export gives one.
+type Payload struct {
+	Kind        PayloadKind  `json:"kind"`
+	Poll        *Poll        `json:"poll,omitempty"`
+	Location    *Location    `json:"location,omitempty"`
+	ContactCard *ContactCard `json:"contactCard,omitempty"`
+}
+
+type Poll struct {
+	Question string       `json:"question"`
+	Options  []PollOption `json:"options"`
+}
+
+type PollOption struct {
+	Text  string `json:"text"`
+	Votes int    `json:"votes"`
+}
+
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	URL       string  `json:"url"`
+}
+
+// ContactCard is a shared contact. Its name comes from the file name, and its
+// phones from the vCard file when the chat is parsed from an export archive.
+type ContactCard struct {
+	Name   string   `json:"name"`
+	Phones []string `json:"phones,omitempty"`
+}
+
+type SystemEventType string
+
+const (
+	SystemEventEncryption          SystemEventType = "encryption"
+	SystemEventGroupCreated        SystemEventType = "groupCreated"
+	SystemEventMemberAdded         SystemEventType = "memberAdded"
+	SystemEventMemberRemoved       SystemEventType = "memberRemoved"
+	SystemEventMemberLeft          SystemEventType = "memberLeft"
+	SystemEventMemberJoined        SystemEventType = "memberJoined" // via an invite link
+	SystemEventSubjectChanged      SystemEventType = "subjectChanged"
+	SystemEventIconChanged         SystemEventType = "iconChanged"
+	SystemEventDescriptionChanged  SystemEventType = "descriptionChanged"
+	SystemEventNumberChanged       SystemEventType = "numberChanged"
+	SystemEventSecurityCodeChanged SystemEventType = "securityCodeChanged"
+	SystemEventDisappearingToggled SystemEventType = "disappearingToggled"
+	SystemEventAdminPromoted       SystemEventType = "adminPromoted"
+)
+
+// SystemEvent is what a system message reports. Actor and Targets hold
+// names as written in the chat, so the export owner appears as "You"/"you".
+type SystemEvent struct {
+	Type     SystemEventType `json:"type"`
+	Actor    *string         `json:"actor,omitempty"`
+	Targets  []string        `json:"targets,omitempty"`
+	Value    string          `json:"value,omitempty"`    // new subject, new number, "on"/"off"...
+	Previous string          `json:"previous,omitempty"` // previous subject, when reported
+}
+
+type DiagnosticKind string
+
+const (
+	DiagnosticDroppedLine        DiagnosticKind = "droppedLine"        // text before the first message
+	DiagnosticInvalidDate        DiagnosticKind = "invalidDate"        // e.g. month 13 once ordered
+	DiagnosticInvalidTime        DiagnosticKind = "invalidTime"        // e.g. 25:61
+	DiagnosticAmbiguousDateOrder DiagnosticKind = "ambiguousDateOrder" // day/month order guessed
+	// DiagnosticUndetectedDateOrder reports that the days-first default was used
+	DiagnosticUndetectedDateOrder DiagnosticKind = "undetectedDateOrder"
+	// DiagnosticAmbiguousLocalTime reports a time repeated when clocks went
+	// back, resolved to its first occurrence
+	DiagnosticAmbiguousLocalTime DiagnosticKind = "ambiguousLocalTime"
+	// DiagnosticNonexistentLocalTime reports a time skipped when clocks went
+	// forward, shifted by the length of the gap
+	DiagnosticNonexistentLocalTime DiagnosticKind = "nonexistentLocalTime"
+	// DiagnosticUnreadableContactCard reports a vCard file of an archive that
+	// could not be read, whose contact card is left without its details
+	DiagnosticUnreadableContactCard DiagnosticKind = "unreadableContactCard"
+)
+
+// Diagnostic reports a problem found while parsing. Line and Offset locate
+// the line at fault, and are 0 for diagnostics about the whole chat.
+type Diagnostic struct {
+	Kind    DiagnosticKind `json:"kind"`
+	Line    int            `json:"line"`
+	Offset  int64          `json:"offset"`
+	Message string         `json:"message"`
+}
+
+type ParseResult struct {
+	Messages    []Message    `json:"messages"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// DateOrderHeuristic names the check of the date format detection that
+// decided the day/month order
+type DateOrderHeuristic string
+
+const (
+	HeuristicAbove12         DateOrderHeuristic = "above12"         // a day or month above 12
+	HeuristicDecreasing      DateOrderHeuristic = "decreasing"      // a day going back within a year
+	HeuristicChangeFrequency DateOrderHeuristic = "changeFrequency" // days change more often than months
+	HeuristicDefault         DateOrderHeuristic = "default"         // nothing detected, days first assumed
+	HeuristicOption          DateOrderHeuristic = "option"          // set by the DaysFirst option
+)
+
+type Layout string
+
+const (
+	LayoutBracketed Layout = "bracketed" // iOS: "[dd/mm/yyyy, hh:mm:ss] "
+	LayoutDash      Layout = "dash"      // Android: "dd/mm/yyyy, hh:mm - "
+	LayoutOther     Layout = "other"
+)
+
+// ChatFormat describes how the dates and headers of a chat are written
+type ChatFormat struct {
+	DaysFirst     bool               `json:"daysFirst"`
+	Heuristic     DateOrderHeuristic `json:"heuristic"`
+	Evidence      int                `json:"evidence"` // dates or changes supporting the heuristic
+	YearFirst     bool               `json:"yearFirst"`
+	YearDigits    int                `json:"yearDigits"`
+	DateSeparator string             `json:"dateSeparator"`
+	Clock12Hour   bool               `json:"clock12Hour"`
+	Seconds       bool               `json:"seconds"`
+	Layout        Layout             `json:"layout"`
+	Locale        string             `json:"locale,omitempty"` // detected or forced locale, if any
+	Messages      int                `json:"messages"`         // number of headers examined
 }
@@ -0,0 +1,237 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Dialect describes how Format and Write write message headers
+type Dialect struct {
+	Layout        Layout `json:"layout"` // LayoutBracketed or LayoutDash
+	DaysFirst     bool   `json:"daysFirst"`
+	YearFirst     bool   `json:"yearFirst"`
+	YearDigits    int    `json:"yearDigits"`    // 2 or 4
+	DateSeparator string `json:"dateSeparator"` // "/" when empty
+	Clock12Hour   bool   `json:"clock12Hour"`
+	Seconds       bool   `json:"seconds"`
+	Locale        string `json:"locale"` // AM/PM markers, English when empty
+}
+
+var (
+	// DialectAndroid writes headers like "13/06/2018, 21:25 - "
+	DialectAndroid = Dialect{Layout: LayoutDash, DaysFirst: true, YearDigits: 4, DateSeparator: "/"}
+	// DialectIOS writes headers like "[13/06/2018, 21:25:15] "
+	DialectIOS = Dialect{Layout: LayoutBracketed, DaysFirst: true, YearDigits: 4, DateSeparator: "/", Seconds: true}
+)
+
+// Dialect returns the dialect writing headers in the detected format
+func (f *ChatFormat) Dialect() Dialect {
+	layout := f.Layout
+	if layout != LayoutBracketed {
+		layout = LayoutDash
+	}
+
+	return Dialect{
+		Layout:        layout,
+		DaysFirst:     f.DaysFirst,
+		YearFirst:     f.YearFirst,
+		YearDigits:    f.YearDigits,
+		DateSeparator: f.DateSeparator,
+		Clock12Hour:   f.Clock12Hour,
+		Seconds:       f.Seconds,
+		Locale:        f.Locale,
+	}
+}
+
+// locale returns the locale of the dialect, English when not set
+func (d Dialect) locale() (*Locale, error) {
+	name := d.Locale
+	if name == "" {
+		name = "en"
+	}
+	locale := LookupLocale(name)
+	if locale == nil {
+		return nil, ErrUnknownLocale
+	}
+	return locale, nil
+}
+
+// formatBody writes the body of a message, with the placeholder of a deleted
+// message or the marker of an edited one in the dialect's locale
+func (d Dialect) formatBody(message Message) (string, error) {
+	if !message.Deleted && !message.Edited {
+		return message.Message, nil
+	}
+
+	locale, err := d.locale()
+	if err != nil {
+		return "", err
+	}
+
+	texts, name := locale.Edited, "edit markers"
+	if message.Deleted {
+		texts, name = locale.Deleted, "deleted message placeholders"
+		if message.FromOwner {
+			texts = locale.DeletedByOwner
+		}
+	}
+	if len(texts) == 0 {
+		return "", fmt.Errorf("parser: locale %q has no %s", locale.Name, name)
+	}
+
+	if message.Deleted {
+		return texts[0], nil
+	}
+	return message.Message + " " + texts[0], nil
+}
+
+// formatDate writes the date part of a header
+func (d Dialect) formatDate(message Message) string {
+	date := message.WallClock
+	if date.IsZero() {
+		date = message.Date
+	}
+
+	year := fmt.Sprintf("%04d", date.Year())
+	if d.YearDigits == 2 {
+		year = year[len(year)-2:]
+	}
+	day := fmt.Sprintf("%02d", date.Day())
+	month := fmt.Sprintf("%02d", int(date.Month()))
+
+	parts := []string{month, day}
+	if d.DaysFirst {
+		parts = []string{day, month}
+	}
+	if d.YearFirst {
+		parts = append([]string{year}, parts...)
+	} else {
+		parts = append(parts, year)
+	}
+
+	separator := d.DateSeparator
+	if separator == "" {
+		separator = "/"
+	}
+	return strings.Join(parts, separator)
+}
+
+// formatTime writes the time part of a header, with the AM/PM marker of the
+// dialect's locale on a 12-hour clock
+func (d Dialect) formatTime(message Message) (string, error) {
+	date := message.WallClock
+	if date.IsZero() {
+		date = message.Date
+	}
+
+	hour := date.Hour()
+	if d.Clock12Hour {
+		hour = hour % 12
+		if hour == 0 {
+			hour = 12
+		}
+	}
+
+	clock := fmt.Sprintf("%02d:%02d", hour, date.Minute())
+	if d.Clock12Hour {
+		clock = fmt.Sprintf("%d:%02d", hour, date.Minute())
+	}
+	if d.Seconds {
+		clock += fmt.Sprintf(":%02d", date.Second())
+	}
+	if !d.Clock12Hour {
+		return clock, nil
+	}
+
+	locale, err := d.locale()
+	if err != nil {
+		return "", err
+	}
+	markers := locale.AM
+	if date.Hour() >= 12 {
+		markers = locale.PM
+	}
+	if len(markers) == 0 {
+		return "", fmt.Errorf("parser: locale %q has no AM/PM markers", locale.Name)
+	}
+
+	if locale.MarkerFirst {
+		return markers[0] + " " + clock, nil
+	}
+	return clock + " " + markers[0], nil
+}
+
+// formatMessage writes a message as it appears in an export, so that parsing
+// it gives the message back
+func (d Dialect) formatMessage(message Message) (string, error) {
+	clock, err := d.formatTime(message)
+	if err != nil {
+		return "", err
+	}
+
+	var header string
+	if d.Layout == LayoutBracketed {
+		header = "[" + d.formatDate(message) + ", " + clock + "] "
+	} else {
+		header = d.formatDate(message) + ", " + clock + " - "
+	}
+
+	text, err := d.formatBody(message)
+	if err != nil {
+		return "", err
+	}
+	// The message text loses one trailing line break when parsed
+	if strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+
+	if message.Author == nil {
+		line := header + text
+		// A system message read as having an author, as with "Anna changed
+		// the subject to "a: b"", is kept a system message by a single
+		// left-to-right mark
+		first := strings.SplitN(line, "\n", 2)[0]
+		if grammar, err := grammarFor(""); err == nil && !strings.Contains(first, "\u200E") {
+			if parsed, ok := grammar.matchLine(first); ok && !parsed.system {
+				line = "\u200E" + line
+			}
+		}
+		return line, nil
+	}
+
+	line := header + *message.Author + ": " + text
+	// A single left-to-right mark on a line makes it an iOS system message
+	if !isNotNewFormatSystemMessage(strings.SplitN(line, "\n", 2)[0]) {
+		line = "\u200E" + line
+	}
+	return line, nil
+}
+
+// Write writes messages in the WhatsApp export format of the dialect, such
+// that parsing the output with ParseString gives the same messages
+func Write(w io.Writer, messages []Message, dialect Dialect) error {
+	for i, message := range messages {
+		line, err := dialect.formatMessage(message)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			line = "\n" + line
+		}
+		if _, err := io.WriteString(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Format returns messages written in the WhatsApp export format of the
+// dialect, like Write
+func Format(messages []Message, dialect Dialect) (string, error) {
+	var b strings.Builder
+	if err := Write(&b, messages, dialect); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,126 @@
+package parser
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestFormat tests that formatted messages parse back to the same messages
+func TestFormat(t *testing.T) {
+	roundTrip := func(t *testing.T, messages []Message, dialect Dialect, options *ParseStringOptions) {
+		t.Helper()
+
+		content, err := Format(messages, dialect)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		parsed, err := ParseString(content, options)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(parsed, messages) {
+			t.Errorf("Expected the formatted chat to parse back to the same messages, got:\n%s", content)
+		}
+	}
+
+	for _, chatExample := range chatExamples {
+		fileContents, err := os.ReadFile(chatExample.filePath)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		content := string(fileContents)
+		options := &ParseStringOptions{ParseAttachments: true}
+
+		messages, err := ParseString(content, options)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		t.Run("Detected dialect "+chatExample.description, func(t *testing.T) {
+			roundTrip(t, messages, DetectFormat(content).Dialect(), options)
+		})
+
+		t.Run("Other dialects "+chatExample.description, func(t *testing.T) {
+			format := DetectFormat(content)
+			daysFirst := format.DaysFirst
+			dialects := []Dialect{
+				DialectAndroid,
+				DialectIOS,
+				{Layout: LayoutDash, YearDigits: 2, DateSeparator: ".", Clock12Hour: true, Locale: "de"},
+				{Layout: LayoutBracketed, YearFirst: true, YearDigits: 4, Clock12Hour: true, Seconds: true, Locale: "ko"},
+			}
+
+			for _, dialect := range dialects {
+				dialect.DaysFirst = daysFirst
+				// Seconds can only be dropped if there are none
+				dialect.Seconds = dialect.Seconds || format.Seconds
+				roundTrip(t, messages, dialect, &ParseStringOptions{ParseAttachments: true, DaysFirst: &daysFirst})
+			}
+		})
+	}
+
+	t.Run("Headers", func(t *testing.T) {
+		author := "Anna"
+		message := Message{Date: time.Date(2018, 6, 3, 0, 5, 9, 0, time.UTC), Author: &author, Message: "m"}
+
+		tests := []struct {
+			dialect Dialect
+			expect  string
+		}{
+			{DialectAndroid, "03/06/2018, 00:05 - Anna: m"},
+			{DialectIOS, "[03/06/2018, 00:05:09] Anna: m"},
+			{Dialect{Layout: LayoutDash, YearDigits: 2, Clock12Hour: true}, "06/03/18, 12:05 AM - Anna: m"},
+			{Dialect{Layout: LayoutDash, YearFirst: true, YearDigits: 4, DateSeparator: "-", Clock12Hour: true, Locale: "ja"}, "2018-06-03, 午前 12:05 - Anna: m"},
+		}
+
+		for _, test := range tests {
+			content, err := Format([]Message{message}, test.dialect)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if content != test.expect {
+				t.Errorf("Expected %q, got %q", test.expect, content)
+			}
+		}
+	})
+
+	t.Run("Multiline messages", func(t *testing.T) {
+		author := "Anna"
+		date := time.Date(2018, 6, 13, 21, 25, 0, 0, time.UTC)
+		messages := []Message{
+			{Date: date, Author: &author, Message: "first\n\nlines\n"},
+			{Date: date, Author: &author, Message: "\u200Esingle mark"},
+			{Date: date, Message: "Anna left", IsSystem: true, Event: &SystemEvent{Type: SystemEventMemberLeft, Actor: &author}},
+			{Date: date, Message: "Anna changed the subject to \"a: b\"", IsSystem: true,
+				Event: &SystemEvent{Type: SystemEventSubjectChanged, Actor: &author, Value: "a: b"}},
+			{Date: date, Author: &author, Message: "last\n\n"},
+		}
+		for i := range messages {
+			messages[i].WallClock = date
+		}
+
+		daysFirst := true
+		roundTrip(t, messages, DialectIOS, &ParseStringOptions{DaysFirst: &daysFirst})
+		roundTrip(t, messages, DialectAndroid, &ParseStringOptions{DaysFirst: &daysFirst})
+	})
+
+	t.Run("Write", func(t *testing.T) {
+		var b bytes.Buffer
+		if err := Write(&b, nil, DialectAndroid); err != nil || b.Len() != 0 {
+			t.Errorf("Expected nothing written for no messages, got %q, %v", b.String(), err)
+		}
+
+		message := Message{Date: time.Now(), Message: "m"}
+		if err := Write(&b, []Message{message}, Dialect{Clock12Hour: true, Locale: "xx"}); err != ErrUnknownLocale {
+			t.Errorf("Expected ErrUnknownLocale, got %v", err)
+		}
+		if err := Write(&b, []Message{message}, Dialect{Clock12Hour: true, Locale: "fr"}); err == nil || !strings.Contains(err.Error(), "fr") {
+			t.Errorf("Expected an error for a locale without markers, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,213 @@
+package parser
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+var (
+	// ErrNoChatFile is returned when an archive contains no chat text file
+	ErrNoChatFile = errors.New("parser: no chat text file found in archive")
+	// ErrAttachmentNotInArchive is returned by Attachment.Open when the
+	// attachment was not linked to an archive entry
+	ErrAttachmentNotInArchive = errors.New("parser: attachment is not in the archive")
+)
+
+// Open opens the archive entry holding the attachment
+func (a *Attachment) Open() (io.ReadCloser, error) {
+	if a.open == nil {
+		return nil, ErrAttachmentNotInArchive
+	}
+	return a.open()
+}
+
+// isArchiveFile returns false for directories and for the metadata macOS
+// adds when compressing an export
+func isArchiveFile(f *zip.File) bool {
+	if f.FileInfo().IsDir() || strings.HasPrefix(f.Name, "__MACOSX/") {
+		return false
+	}
+	return !strings.HasPrefix(path.Base(f.Name), "._")
+}
+
+// findChatFile picks the chat log of an export archive: _chat.txt on iOS,
+// "WhatsApp Chat with X.txt" (or its translation) on Android, and otherwise
+// the only text file in the archive
+func findChatFile(files []*zip.File) *zip.File {
+	var textFiles []*zip.File
+	for _, f := range files {
+		if isArchiveFile(f) && strings.EqualFold(path.Ext(f.Name), ".txt") {
+			textFiles = append(textFiles, f)
+		}
+	}
+
+	for _, f := range textFiles {
+		if path.Base(f.Name) == "_chat.txt" {
+			return f
+		}
+	}
+	for _, f := range textFiles {
+		if strings.Contains(strings.ToLower(path.Base(f.Name)), "whatsapp") {
+			return f
+		}
+	}
+	if len(textFiles) == 1 {
+		return textFiles[0]
+	}
+
+	return nil
+}
+
+// parseArchive parses the chat log of an export archive and links the
+// attachments to their entries, using openEntry to build their openers
+func parseArchive(archive *zip.Reader, options *ParseStringOptions, openEntry func(*zip.File) func() (io.ReadCloser, error)) (*ParseResult, error) {
+	chatFile := findChatFile(archive.File)
+	if chatFile == nil {
+		return nil, ErrNoChatFile
+	}
+
+	rc, err := chatFile.Open()
+	if err != nil {
+		return nil, err
+	}
+	content, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := Parse(string(content), options)
+	if err != nil {
+		return nil, err
+	}
+	messages := result.Messages
+
+	entries := make(map[string]*zip.File)
+	for _, f := range archive.File {
+		if isArchiveFile(f) && f != chatFile {
+			entries[path.Base(f.Name)] = f
+		}
+	}
+
+	for i := range messages {
+		attachment := messages[i].Attachment
+		if attachment == nil {
+			continue
+		}
+
+		entry, ok := entries[attachment.FileName]
+		if !ok {
+			continue
+		}
+		attachment.Path = entry.Name
+		attachment.Size = int64(entry.UncompressedSize64)
+		attachment.open = openEntry(entry)
+
+		if payload := messages[i].Payload; payload != nil && payload.ContactCard != nil {
+			if err := readArchiveVCard(entry, payload.ContactCard); err != nil {
+				result.Diagnostics = append(result.Diagnostics, Diagnostic{
+					Kind:    DiagnosticUnreadableContactCard,
+					Message: fmt.Sprintf("unreadable contact card %s: %v", entry.Name, err),
+				})
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// readArchiveVCard fills in a contact card from its vCard file in an archive
+func readArchiveVCard(entry *zip.File, card *ContactCard) error {
+	rc, err := entry.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return readVCard(rc, card)
+}
+
+// ParseArchive parses a WhatsApp "Export chat" archive read from r, and
+// reports the problems found along with the messages. The chat log inside is
+// parsed with Parse, and when ParseAttachments is set each attachment found
+// in the archive gets its Path, Size and an opener, and contact cards get the
+// phones of their vCard file. The attachments can be opened for as long as r
+// stays readable.
+func ParseArchive(r io.ReaderAt, size int64, options *ParseStringOptions) (*ParseResult, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseArchive(archive, options, func(f *zip.File) func() (io.ReadCloser, error) {
+		return f.Open
+	})
+}
+
+// ParseZip parses a WhatsApp "Export chat" archive read from r like
+// ParseArchive, and returns the messages
+func ParseZip(r io.ReaderAt, size int64, options *ParseStringOptions) ([]Message, error) {
+	result, err := ParseArchive(r, size, options)
+	if err != nil {
+		return nil, err
+	}
+	return result.Messages, nil
+}
+
+// archiveEntryReader closes the archive an entry was opened from along with
+// the entry itself
+type archiveEntryReader struct {
+	io.ReadCloser
+	archive io.Closer
+}
+
+func (r archiveEntryReader) Close() error {
+	err := r.ReadCloser.Close()
+	if closeErr := r.archive.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// ParseZipFile parses the WhatsApp "Export chat" archive at the given path
+// like ParseZip. The archive is closed before returning and reopened by each
+// call to Attachment.Open.
+func ParseZipFile(name string, options *ParseStringOptions) ([]Message, error) {
+	archive, err := zip.OpenReader(name)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	result, err := parseArchive(&archive.Reader, options, func(f *zip.File) func() (io.ReadCloser, error) {
+		entryName := f.Name
+		return func() (io.ReadCloser, error) {
+			archive, err := zip.OpenReader(name)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, f := range archive.File {
+				if f.Name != entryName {
+					continue
+				}
+				rc, err := f.Open()
+				if err != nil {
+					archive.Close()
+					return nil, err
+				}
+				return archiveEntryReader{ReadCloser: rc, archive: archive}, nil
+			}
+
+			archive.Close()
+			return nil, ErrAttachmentNotInArchive
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.Messages, nil
+}
@@ -0,0 +1,192 @@
+package parser
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// makeZip builds an archive holding the given files
+func makeZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+// TestParseZip tests parsing export archives
+func TestParseZip(t *testing.T) {
+	chat := "[29/11/2018, 10:51:11] Josh: <attached: 00000012-PHOTO-2018-11-29.jpg>\n" +
+		"[29/11/2018, 10:51:18] Marthy: Nice"
+	archive := makeZip(t, map[string]string{
+		"_chat.txt":                     chat,
+		"notes.txt":                     "not the chat",
+		"00000012-PHOTO-2018-11-29.jpg": "jpeg bytes",
+		"__MACOSX/._chat.txt":           "metadata",
+	})
+	options := ParseStringOptions{ParseAttachments: true}
+
+	t.Run("Links attachments", func(t *testing.T) {
+		messages, err := ParseZip(bytes.NewReader(archive), int64(len(archive)), &options)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(messages) != 2 {
+			t.Fatalf("Expected 2 messages, got %d", len(messages))
+		}
+
+		attachment := messages[0].Attachment
+		if attachment == nil || attachment.Path != "00000012-PHOTO-2018-11-29.jpg" || attachment.Size != 10 {
+			t.Fatalf("Expected attachment linked to its entry, got %+v", attachment)
+		}
+
+		rc, err := attachment.Open()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer rc.Close()
+		content, _ := io.ReadAll(rc)
+		if string(content) != "jpeg bytes" {
+			t.Errorf("Expected attachment content %q, got %q", "jpeg bytes", content)
+		}
+	})
+
+	t.Run("Reopens archive files", func(t *testing.T) {
+		name := filepath.Join(t.TempDir(), "WhatsApp Chat with Josh.zip")
+		if err := os.WriteFile(name, archive, 0o644); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		messages, err := ParseZipFile(name, &options)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		rc, err := messages[0].Attachment.Open()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		content, _ := io.ReadAll(rc)
+		if err := rc.Close(); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if string(content) != "jpeg bytes" {
+			t.Errorf("Expected attachment content %q, got %q", "jpeg bytes", content)
+		}
+	})
+
+	t.Run("Finds Android chat files", func(t *testing.T) {
+		archive := makeZip(t, map[string]string{
+			"WhatsApp Chat with Andrew.txt": "3/10/25, 16:40 - Andrew: IMG-1.jpg (file attached)",
+			"notes.txt":                     "not the chat",
+		})
+
+		messages, err := ParseZip(bytes.NewReader(archive), int64(len(archive)), &options)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(messages) != 1 {
+			t.Fatalf("Expected 1 message, got %d", len(messages))
+		}
+		if _, err := messages[0].Attachment.Open(); !errors.Is(err, ErrAttachmentNotInArchive) {
+			t.Errorf("Expected ErrAttachmentNotInArchive, got %v", err)
+		}
+	})
+
+	t.Run("ParseArchive", func(t *testing.T) {
+		archive := makeZip(t, map[string]string{"_chat.txt": "stray line\n" + chat})
+
+		result, err := ParseArchive(bytes.NewReader(archive), int64(len(archive)), &options)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(result.Messages) != 2 || len(result.Diagnostics) == 0 || result.Diagnostics[0].Kind != DiagnosticDroppedLine {
+			t.Errorf("Expected 2 messages and a dropped line, got %+v", result)
+		}
+	})
+
+	t.Run("Contact cards", func(t *testing.T) {
+		archive := makeZip(t, map[string]string{
+			"_chat.txt": "\u200E[29/11/2018, 10:51:11] Josh: \u200E<attached: 00000016-John Doe.vcf>",
+			"00000016-John Doe.vcf": "BEGIN:VCARD\r\nVERSION:3.0\r\nFN:John Doe\r\n" +
+				"item1.TEL;type=CELL;waid=48123456789:+48 123 456\r\n 789\r\nTEL:+1 555 0100\r\nEND:VCARD\r\n",
+		})
+
+		messages, err := ParseZip(bytes.NewReader(archive), int64(len(archive)), &options)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		expect := &ContactCard{Name: "John Doe", Phones: []string{"+48 123 456789", "+1 555 0100"}}
+		if payload := messages[0].Payload; payload == nil || !reflect.DeepEqual(payload.ContactCard, expect) {
+			t.Errorf("Expected contact card %+v, got %+v", expect, payload)
+		}
+	})
+
+	t.Run("Broken contact card", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := zip.NewWriter(&buf)
+		f, err := w.Create("_chat.txt")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		f.Write([]byte("\u200E[29/11/2018, 10:51:11] Josh: \u200E<attached: 00000016-John Doe.vcf>"))
+		// The checksum does not match the content, so reading it fails
+		vcard := "BEGIN:VCARD\r\nFN:John Doe\r\nTEL:+1 555 0100\r\nEND:VCARD\r\n"
+		f, err = w.CreateRaw(&zip.FileHeader{
+			Name:               "00000016-John Doe.vcf",
+			Method:             zip.Store,
+			CRC32:              1,
+			CompressedSize64:   uint64(len(vcard)),
+			UncompressedSize64: uint64(len(vcard)),
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		f.Write([]byte(vcard))
+		if err := w.Close(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		archive := buf.Bytes()
+
+		result, err := ParseArchive(bytes.NewReader(archive), int64(len(archive)), &options)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expect := &ContactCard{Name: "John Doe"}
+		if payload := result.Messages[0].Payload; payload == nil || !reflect.DeepEqual(payload.ContactCard, expect) {
+			t.Errorf("Expected contact card %+v, got %+v", expect, payload)
+		}
+		if len(result.Diagnostics) != 1 || result.Diagnostics[0].Kind != DiagnosticUnreadableContactCard {
+			t.Errorf("Expected an unreadable contact card, got %+v", result.Diagnostics)
+		}
+	})
+
+	t.Run("No chat file", func(t *testing.T) {
+		archive := makeZip(t, map[string]string{"a.txt": "", "b.txt": ""})
+
+		_, err := ParseZip(bytes.NewReader(archive), int64(len(archive)), &options)
+		if !errors.Is(err, ErrNoChatFile) {
+			t.Errorf("Expected ErrNoChatFile, got %v", err)
+		}
+	})
+}